@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"taptap/biz/cell"
+	"taptap/biz/sim"
+	"taptap/biz/view"
+)
+
+var (
+	games     = flag.Int("games", 1000, "bench 每种棋盘玩几局")
+	benchSeed = flag.Int64("seed", 1, "bench 第一局的种子，后面的局依次加一")
+)
+
+// defaultBoards 初级、中级、高级
+var defaultBoards = []string{"9x9x10", "16x16x40", "16x30x99"}
+
+// board 一种棋盘，行x列x雷
+type board struct {
+	rows, cols, mines int
+}
+
+func parseBoard(s string) (b board, err error) {
+	_, err = fmt.Sscanf(s, "%dx%dx%d", &b.rows, &b.cols, &b.mines)
+	if err == nil && (b.rows < 1 || b.cols < 1 || b.mines < 0 || b.mines >= b.rows*b.cols) {
+		err = fmt.Errorf("bad board %q", s)
+	}
+	return
+}
+
+// benchStats 一种棋盘上所有局加起来
+type benchStats struct {
+	games, wins int
+	guesses     int               // 猜了几次，第一下不算
+	moves       int               // 求解了几次
+	solve       time.Duration     // 求解一共花的时间
+	rules       map[view.Rule]int // 每条规则推出来几个格子
+	errors      int               // 求解报矛盾的次数，不应该有
+}
+
+// bench 在内存里的扫雷上玩很多局，只用 view.Solve 和 view.Guess，不用截图
+// 参数是棋盘，格式是 行x列x雷，不给就跑初级、中级、高级
+func bench(args []string) {
+	if len(args) == 0 {
+		args = defaultBoards
+	}
+	fmt.Printf("%-10s %6s %7s %8s %8s %10s  %s\n", "board", "games", "win%", "guesses", "moves", "per move", "deductions")
+	for _, arg := range args {
+		b, err := parseBoard(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		st := &benchStats{rules: make(map[view.Rule]int)}
+		for i := 0; i < *games; i++ {
+			benchGame(sim.NewGame(b.rows, b.cols, b.mines, *benchSeed+int64(i)), st)
+		}
+		fmt.Printf("%-10s %6d %6.2f%% %8.2f %8.2f %10v  %s\n",
+			arg, st.games,
+			percent(st.wins, st.games),
+			float64(st.guesses)/float64(st.games),
+			float64(st.moves)/float64(st.games),
+			st.perMove(),
+			st.ruleString(),
+		)
+		if st.errors > 0 {
+			fmt.Printf("%-10s %v contradictions\n", "", st.errors)
+		}
+	}
+}
+
+// benchGame 第一下点中间，推不出来就猜最不像雷的格子，直到输赢
+func benchGame(g *sim.Game, st *benchStats) {
+	st.games++
+	g.Open(g.Rows()/2, g.Cols()/2)
+	for !g.Won() && !g.Lost() {
+		v := g.View()
+		start := time.Now()
+		ret, err := view.Solve(v, view.Options{Mines: g.Mines(), InPlace: true, Queue: true})
+		st.solve += time.Since(start)
+		st.moves++
+		if err != nil {
+			st.errors++
+			return
+		}
+		for _, d := range ret.Mines {
+			st.rules[d.Rule]++
+			p := d.Cell.Pt()
+			if g.State(p.X, p.Y) != cell.Flag {
+				g.Flag(p.X, p.Y)
+			}
+		}
+		for _, d := range ret.Safe {
+			st.rules[d.Rule]++
+			p := d.Cell.Pt()
+			g.Open(p.X, p.Y)
+		}
+		if len(ret.Safe) > 0 {
+			continue
+		}
+		c, _ := view.Guess(v, view.Options{Mines: g.Mines()})
+		if c == nil {
+			return
+		}
+		st.guesses++
+		p := c.Pt()
+		g.Open(p.X, p.Y)
+	}
+	if g.Won() {
+		st.wins++
+	}
+}
+
+func (st *benchStats) perMove() time.Duration {
+	if st.moves == 0 {
+		return 0
+	}
+	return (st.solve / time.Duration(st.moves)).Round(time.Microsecond)
+}
+
+// ruleString 每条规则推出来的格子占多少
+func (st *benchStats) ruleString() string {
+	total := 0
+	for _, n := range st.rules {
+		total += n
+	}
+	var list []string
+	for rule := view.Rule(1); rule&view.RuleAll != 0; rule <<= 1 {
+		list = append(list, fmt.Sprintf("%v %.1f%%", rule, percent(st.rules[rule], total)))
+	}
+	return strings.Join(list, " ")
+}
+
+func percent(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) * 100 / float64(b)
+}
@@ -0,0 +1,178 @@
+// Package annotate 把识别和求解的结果画成一张 PNG，报 bug 的时候附上
+package annotate
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+
+	"gocv.io/x/gocv"
+)
+
+// Kind 格子上标的是什么
+type Kind int
+
+const (
+	Mine      Kind = iota // 推出来是雷
+	Safe                  // 推出来不是雷
+	Guess                 // 推不出来的时候要猜的格子
+	Uncertain             // 识别得没把握的格子
+)
+
+var kinds = []struct {
+	name  string
+	color color.RGBA
+}{
+	Mine:      {"mine", color.RGBA{255, 0, 0, 0}},
+	Safe:      {"safe", color.RGBA{0, 200, 0, 0}},
+	Guess:     {"guess", color.RGBA{0, 120, 255, 0}},
+	Uncertain: {"uncertain", color.RGBA{255, 200, 0, 0}},
+}
+
+func (k Kind) String() string {
+	return kinds[k].name
+}
+
+const (
+	margin = 40 // 上面和左边留出来写行号列号
+	legend = 40 // 下面写图例
+)
+
+var (
+	white      = gocv.NewScalar(255, 255, 255, 0)
+	textColor  = color.RGBA{0, 0, 0, 0}
+	boardColor = color.RGBA{60, 60, 60, 0}
+	cellColor  = color.RGBA{230, 230, 230, 0}
+)
+
+// Grid 网格线在截图上的位置，和 main 里 getGrid 出来的一样
+type Grid struct {
+	Rows []int // 横线的 y，就是 x_list
+	Cols []int // 竖线的 x，就是 y_list
+}
+
+// Rect 第 row 行第 col 列的格子在截图上的位置，出了网格返回 false
+func (g Grid) Rect(row, col int) (image.Rectangle, bool) {
+	if row < 0 || col < 0 || row+1 >= len(g.Rows) || col+1 >= len(g.Cols) {
+		return image.Rectangle{}, false
+	}
+	return image.Rect(g.Cols[col], g.Rows[row], g.Cols[col+1], g.Rows[row+1]), true
+}
+
+type mark struct {
+	kind Kind
+	cell *cell.Cell
+}
+
+// Result 要画在图上的东西
+type Result struct {
+	Grid  Grid
+	View  *view.View // Compare 用它画识别出来的棋盘
+	marks []mark
+}
+
+// Add 标上一些格子，同一个格子可以标好几种
+func (r *Result) Add(kind Kind, list ...*cell.Cell) {
+	for _, c := range list {
+		if c != nil {
+			r.marks = append(r.marks, mark{kind, c})
+		}
+	}
+}
+
+// Render 截图加上行号列号、标记和图例，调用的人负责 Close
+func Render(src gocv.Mat, r *Result) gocv.Mat {
+	dst := gocv.NewMatWithSizeFromScalar(white, src.Rows()+margin+legend, src.Cols()+margin, gocv.MatTypeCV8UC3)
+	region := dst.Region(image.Rect(margin, margin, margin+src.Cols(), margin+src.Rows()))
+	src.CopyTo(&region)
+	region.Close()
+	r.draw(&dst)
+	return dst
+}
+
+// Compare 左边是识别出来的棋盘，右边是 Render 的截图，两边标记一样，调用的人负责 Close
+func Compare(src gocv.Mat, r *Result) gocv.Mat {
+	board := gocv.NewMatWithSizeFromScalar(white, src.Rows()+margin+legend, src.Cols()+margin, gocv.MatTypeCV8UC3)
+	defer board.Close()
+	gocv.Rectangle(&board, image.Rect(margin, margin, margin+src.Cols(), margin+src.Rows()), boardColor, -1)
+	if r.View != nil {
+		for i := 0; i < r.View.Rows(); i++ {
+			for j := 0; j < r.View.Cols(); j++ {
+				if rect, ok := r.Grid.Rect(i, j); ok {
+					drawState(&board, rect.Add(image.Pt(margin, margin)), r.View.GetCell(i, j))
+				}
+			}
+		}
+	}
+	r.draw(&board)
+
+	right := Render(src, r)
+	defer right.Close()
+	dst := gocv.NewMat()
+	gocv.Hconcat(board, right, &dst)
+	return dst
+}
+
+// Write 写成文件，文件名决定格式，一般用 .png
+func Write(name string, m gocv.Mat) error {
+	if !gocv.IMWrite(name, m) {
+		return fmt.Errorf("annotate: write %v", name)
+	}
+	return nil
+}
+
+// draw 在留好边的图上画行号列号、标记和图例
+func (r *Result) draw(dst *gocv.Mat) {
+	off := image.Pt(margin, margin)
+	g := r.Grid
+	if len(g.Rows) > 1 && len(g.Cols) > 1 {
+		for j := 0; j+1 < len(g.Cols); j++ {
+			x := margin + (g.Cols[j]+g.Cols[j+1])/2 - 6
+			gocv.PutText(dst, fmt.Sprint(j), image.Pt(x, margin+g.Rows[0]-8), gocv.FontHersheySimplex, 0.5, textColor, 1)
+		}
+		for i := 0; i+1 < len(g.Rows); i++ {
+			y := margin + (g.Rows[i]+g.Rows[i+1])/2 + 5
+			gocv.PutText(dst, fmt.Sprint(i), image.Pt(margin+g.Cols[0]-28, y), gocv.FontHersheySimplex, 0.5, textColor, 1)
+		}
+	}
+	for _, m := range r.marks {
+		p := m.cell.Pt()
+		if rect, ok := g.Rect(p.X, p.Y); ok {
+			drawMark(dst, rect.Add(off), m.kind)
+		}
+	}
+	y := dst.Rows() - legend
+	for k := range kinds {
+		x := 10 + k*140
+		drawMark(dst, image.Rect(x, y+8, x+24, y+32), Kind(k))
+		gocv.PutText(dst, Kind(k).String(), image.Pt(x+30, y+26), gocv.FontHersheySimplex, 0.6, textColor, 1)
+	}
+}
+
+// drawMark 雷和不是雷画圆圈，要猜的画方框，没把握的画细一点的方框
+func drawMark(dst *gocv.Mat, rect image.Rectangle, kind Kind) {
+	c := kinds[kind].color
+	center := image.Pt((rect.Min.X+rect.Max.X)/2, (rect.Min.Y+rect.Max.Y)/2)
+	switch kind {
+	case Mine, Safe:
+		gocv.Circle(dst, center, rect.Dx()/3, c, 3)
+	case Guess:
+		gocv.Rectangle(dst, rect.Inset(3), c, 3)
+	default:
+		gocv.Rectangle(dst, rect.Inset(1), c, 2)
+	}
+}
+
+// drawState 识别出来的格子：没开的是浅色，别的在深色上写 Byte
+func drawState(dst *gocv.Mat, rect image.Rectangle, c *cell.Cell) {
+	if c.IsUnknown() {
+		gocv.Rectangle(dst, rect.Inset(2), cellColor, -1)
+		return
+	}
+	scale := float64(rect.Dy()) / 40
+	p := image.Pt(rect.Min.X+rect.Dx()/3, rect.Max.Y-rect.Dy()/4)
+	gocv.PutText(dst, c.S(), p, gocv.FontHersheySimplex, scale, cellColor, 2)
+}
@@ -0,0 +1,56 @@
+package annotate
+
+import (
+	"image"
+	"testing"
+
+	"taptap/biz/view"
+
+	"gocv.io/x/gocv"
+)
+
+func TestGridRect(t *testing.T) {
+	g := Grid{Rows: []int{10, 20, 30}, Cols: []int{5, 15}}
+	if r, ok := g.Rect(1, 0); !ok || r != image.Rect(5, 20, 15, 30) {
+		t.Fatalf("got %v %v", r, ok)
+	}
+	for _, p := range []image.Point{{2, 0}, {0, 1}, {-1, 0}} {
+		if _, ok := g.Rect(p.X, p.Y); ok {
+			t.Fatalf("%v: want outside", p)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	v, err := view.Parse(`
+		1_
+		__
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{Grid: Grid{Rows: []int{100, 150, 200}, Cols: []int{50, 100, 150}}, View: v}
+	r.Add(Mine, v.GetCell(0, 1))
+	r.Add(Safe, v.GetCell(1, 0), nil)
+	r.Add(Guess, v.GetCell(1, 1))
+	r.Add(Uncertain, v.GetCell(0, 0))
+	if len(r.marks) != 4 {
+		t.Fatalf("got %v marks", len(r.marks))
+	}
+
+	src := gocv.NewMatWithSize(300, 200, gocv.MatTypeCV8UC3)
+	defer src.Close()
+	dst := Render(src, r)
+	defer dst.Close()
+	if dst.Rows() != 300+margin+legend || dst.Cols() != 200+margin {
+		t.Fatalf("render %vx%v", dst.Rows(), dst.Cols())
+	}
+	both := Compare(src, r)
+	defer both.Close()
+	if both.Rows() != dst.Rows() || both.Cols() != 2*dst.Cols() {
+		t.Fatalf("compare %vx%v", both.Rows(), both.Cols())
+	}
+	if Guess.String() != "guess" {
+		t.Fatal(Guess.String())
+	}
+}
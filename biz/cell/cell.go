@@ -51,20 +51,25 @@ func (p Point) GetSub() (list []image.Point) {
 	return
 }
 
+// Cell 一个格子，小图归格子所有，用完要 Close
 type Cell struct {
-	row      int       // 相对坐标 row
-	col      int       // 相对坐标 col
-	mat      *gocv.Mat // 这个坐标的小图
-	centerX  int       // 这个小图的中心点x
-	centerY  int       // 小图中心点y
-	ret      byte      // 小图的识别内容
-	retIndex int
+	row     int       // 相对坐标 row
+	col     int       // 相对坐标 col
+	mat     *gocv.Mat // 这个坐标的小图
+	centerX int       // 这个小图的中心点x
+	centerY int       // 小图中心点y
+	state   State     // 小图的识别内容
 }
 
 func (c *Cell) Pt() Point {
 	return Pt(c.row, c.col)
 }
 
+// Mat 这个格子的小图，Parse 出来的格子没有，是 nil
+func (c *Cell) Mat() *gocv.Mat {
+	return c.mat
+}
+
 func (c *Cell) Step() int {
 	s := c.mat.Size()
 	r := s[0] / 3
@@ -75,66 +80,118 @@ func (c *Cell) Point() image.Point {
 	return image.Point{c.centerX, c.centerY}
 }
 
-func New(row, col, x, y int, mat *gocv.Mat, ret byte, retIndex int) *Cell {
+func New(row, col, x, y int, mat *gocv.Mat, state State) *Cell {
 	return &Cell{
-		row:      row,
-		col:      col,
-		mat:      mat,
-		centerX:  x,
-		centerY:  y,
-		ret:      ret,
-		retIndex: retIndex,
+		row:     row,
+		col:     col,
+		mat:     mat,
+		centerX: x,
+		centerY: y,
+		state:   state,
 	}
 }
 
+// Close 释放小图，关多次也没事
+func (c *Cell) Close() {
+	if c.mat != nil {
+		c.mat.Close()
+	}
+}
+
+// Move 复制一份放到新的坐标上，小图和识别结果共用，两个格子关哪个都行
+func (c *Cell) Move(row, col int) *Cell {
+	n := *c
+	n.row = row
+	n.col = col
+	return &n
+}
+
+func (c *Cell) State() State {
+	return c.state
+}
+
+func (c *Cell) SetState(state State) {
+	c.state = state
+}
+
 func (c *Cell) Byte() byte {
-	return c.ret
+	return c.state.Byte()
 }
 
 func (c *Cell) S() string {
-	return string([]byte{c.ret})
+	return string([]byte{c.Byte()})
 }
 
 func (c *Cell) String() string {
 	s := fmt.Sprintf("%v,%v:", c.row, c.col)
-	return "(" + s + string([]byte{c.ret}) + ")"
+	return "(" + s + c.S() + ")"
 }
 
-func (c *Cell) Int() int {
-	return c.retIndex
+// Num 翻开的数字，不是数字就报错
+func (c *Cell) Num() (int, error) {
+	return c.state.Num()
+}
+
+// IsNum 翻开的数字格子
+func (c *Cell) IsNum() bool {
+	return c.state.IsNum()
 }
 
 func (c *Cell) IsUnTap() bool {
-	return c.IsFlag() || c.IsUnknown()
+	return c.IsMineKnown() || c.IsUnknown()
 }
 
+// IsUnknown 没开的格子，标了问号的也算
 func (c *Cell) IsUnknown() bool {
-	return c.ret == '_'
+	return c.state == Unknown || c.state == Question
 }
 
+// IsFlag 屏幕上插了旗子的格子
 func (c *Cell) IsFlag() bool {
-	return c.ret == 'f'
+	return c.state == Flag
 }
 
-func (c *Cell) SetFlag() {
-	c.ret = 'f'
-	c.retIndex = -1
-	fmt.Println("find boom at", c.row, c.col)
+// IsMarked 推出来是雷，但是屏幕上没插旗子
+func (c *Cell) IsMarked() bool {
+	return c.state == Marked
 }
 
-func (c *Cell) SetUnknown() {
-	c.ret = '_'
-	c.retIndex = -3
-	fmt.Println("find unk  at", c.row, c.col)
+// IsMineKnown 插了旗子或者推出来是雷，推理的时候两个一样算
+func (c *Cell) IsMineKnown() bool {
+	return c.IsFlag() || c.IsMarked()
+}
+
+// IsQuestion 标了问号的格子
+func (c *Cell) IsQuestion() bool {
+	return c.state == Question
+}
+
+// IsWrongFlag 输了以后被打叉的旗子，下面其实没有雷
+func (c *Cell) IsWrongFlag() bool {
+	return c.state == WrongFlag
+}
+
+// IsMine 输了以后翻开的雷
+func (c *Cell) IsMine() bool {
+	return c.state == Mine
 }
 
-func (c *Cell) Tap() {
-	fmt.Println("find num  at", c.row, c.col)
+// IsExploded 踩到的那个雷
+func (c *Cell) IsExploded() bool {
+	return c.state == Exploded
+}
+
+func (c *Cell) SetFlag() {
+	c.state = Flag
+}
+
+func (c *Cell) SetUnknown() {
+	c.state = Unknown
 }
 
 func (a *Cell) Gt(b *Cell) bool {
 	// a>b
-	return a.retIndex > b.retIndex
+	return a.state > b.state
 }
 
 func Index(x, y, base int) int {
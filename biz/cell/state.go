@@ -0,0 +1,113 @@
+package cell
+
+import "fmt"
+
+// State 格子的状态，0-8 是翻开的数字，别的都是负数
+type State int8
+
+const (
+	Unknown   State = -3 // 没开的格子
+	Flag      State = -1 // 插了旗子
+	Question  State = -4 // 标了问号，也是没开的格子
+	WrongFlag State = -5 // 输了以后被打叉的旗子
+	Mine      State = -6 // 输了以后翻开的雷
+	Exploded  State = -7 // 踩到的那个雷
+	Marked    State = -8 // 推出来是雷，只记在程序里，屏幕上没有插旗子
+	Opened    State = -9 // 翻开了，数字不在 0-8，只有变种扫雷里有，数字记在 view.View 里
+)
+
+var stateBytes = map[State]byte{
+	Unknown:   '_',
+	Flag:      'f',
+	Question:  '?',
+	WrongFlag: 'x',
+	Mine:      '*',
+	Exploded:  '#',
+	Marked:    'm',
+	Opened:    'o',
+}
+
+var stateNames = map[State]string{
+	Unknown:   "unknown",
+	Flag:      "flag",
+	Question:  "question",
+	WrongFlag: "wrong flag",
+	Mine:      "mine",
+	Exploded:  "exploded",
+	Marked:    "marked",
+	Opened:    "opened",
+}
+
+// Number 翻开的数字 n
+func Number(n int) (State, error) {
+	if n < 0 || n > 8 {
+		return Unknown, fmt.Errorf("cell: bad number %v", n)
+	}
+	return State(n), nil
+}
+
+// ParseState 从棋盘上的字符得到状态，和 Byte 相反
+func ParseState(b byte) (State, error) {
+	if '0' <= b && b <= '8' {
+		return State(b - '0'), nil
+	}
+	for s, c := range stateBytes {
+		if c == b {
+			return s, nil
+		}
+	}
+	return Unknown, fmt.Errorf("cell: bad state %q", b)
+}
+
+// IsNum 是不是翻开的数字
+func (s State) IsNum() bool {
+	return 0 <= s && s <= 8
+}
+
+// Num 翻开的数字，不是数字就报错
+func (s State) Num() (int, error) {
+	if !s.IsNum() {
+		return 0, fmt.Errorf("cell: %v is not a number", s)
+	}
+	return int(s), nil
+}
+
+// Byte 棋盘上显示的字符
+func (s State) Byte() byte {
+	if s.IsNum() {
+		return '0' + byte(s)
+	}
+	if b, ok := stateBytes[s]; ok {
+		return b
+	}
+	return '!'
+}
+
+func (s State) String() string {
+	if s.IsNum() {
+		return string(s.Byte())
+	}
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", int8(s))
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	if b := s.Byte(); b != '!' {
+		return []byte{b}, nil
+	}
+	return nil, fmt.Errorf("cell: bad state %d", int8(s))
+}
+
+func (s *State) UnmarshalText(text []byte) error {
+	if len(text) != 1 {
+		return fmt.Errorf("cell: bad state %q", text)
+	}
+	v, err := ParseState(text[0])
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,59 @@
+package cell
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStateText(t *testing.T) {
+	for _, b := range []byte("012345678_f?x*#") {
+		s, err := ParseState(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := s.MarshalText()
+		if err != nil || string(text) != string(b) {
+			t.Fatalf("%c: got %q %v", b, text, err)
+		}
+		var back State
+		if err := back.UnmarshalText(text); err != nil || back != s {
+			t.Fatalf("%c: got %v %v", b, back, err)
+		}
+	}
+	if _, err := ParseState('9'); err == nil {
+		t.Fatal("9 is not a state")
+	}
+	if _, err := State(42).MarshalText(); err == nil {
+		t.Fatal("42 is not a state")
+	}
+}
+
+func TestStateNum(t *testing.T) {
+	s, err := Number(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Num(); err != nil || n != 3 {
+		t.Fatalf("got %v %v", n, err)
+	}
+	if _, err := Flag.Num(); err == nil {
+		t.Fatal("flag is not a number")
+	}
+	if _, err := Number(9); err == nil {
+		t.Fatal("9 is not a number")
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	buf, err := json.Marshal([]State{Unknown, Flag, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `["_","f","2"]` {
+		t.Fatalf("got %s", buf)
+	}
+	var list []State
+	if err := json.Unmarshal(buf, &list); err != nil || len(list) != 3 || list[2] != 2 {
+		t.Fatalf("got %v %v", list, err)
+	}
+}
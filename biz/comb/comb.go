@@ -0,0 +1,104 @@
+package comb
+
+// Combinations 从 n 个里取 k 个，按字典序一个一个给出来，不会一次全生成出来
+//
+//	c := comb.New(5, 2)
+//	for c.Next() {
+//		c.Indices() // [0 1] [0 2] ... [3 4]
+//	}
+type Combinations struct {
+	n, k    int
+	idx     []int
+	mask    []bool
+	started bool
+	done    bool
+}
+
+func New(n, k int) *Combinations {
+	if n < 0 {
+		n = 0
+	}
+	if k < 0 || k > n {
+		return &Combinations{n: n, done: true}
+	}
+	return &Combinations{
+		n:    n,
+		k:    k,
+		idx:  make([]int, k),
+		mask: make([]bool, n),
+	}
+}
+
+// Next 换到下一个组合，没有了返回 false
+func (c *Combinations) Next() bool {
+	if c.done {
+		return false
+	}
+	if !c.started {
+		c.started = true
+		for i := range c.idx {
+			c.idx[i] = i
+		}
+		c.setMask()
+		return true
+	}
+	// 从右往左找第一个还能往后挪的
+	i := c.k - 1
+	for i >= 0 && c.idx[i] == c.n-c.k+i {
+		i--
+	}
+	if i < 0 {
+		c.done = true
+		return false
+	}
+	c.idx[i]++
+	for j := i + 1; j < c.k; j++ {
+		c.idx[j] = c.idx[j-1] + 1
+	}
+	c.setMask()
+	return true
+}
+
+// Skip 前 i+1 个选的已经不行了，下一次 Next 跳过所有以它们开头的组合，用来剪枝
+func (c *Combinations) Skip(i int) {
+	if !c.started || c.done || i < 0 || i >= c.k {
+		return
+	}
+	for j := i + 1; j < c.k; j++ {
+		c.idx[j] = c.n - c.k + j
+	}
+}
+
+// Indices 选中的下标，从小到大，下一次 Next 会改掉，要留着的话自己复制一份
+func (c *Combinations) Indices() []int {
+	return c.idx
+}
+
+// Mask 长度一直是 n，选中的是 true，下一次 Next 会改掉
+func (c *Combinations) Mask() []bool {
+	return c.mask
+}
+
+func (c *Combinations) setMask() {
+	for i := range c.mask {
+		c.mask[i] = false
+	}
+	for _, i := range c.idx {
+		c.mask[i] = true
+	}
+}
+
+// Count 从 n 个里取 k 个一共有多少种
+func Count(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	ret := 1
+	for i := 1; i <= k; i++ {
+		ret = ret * (n - k + i) / i
+	}
+	return ret
+}
@@ -0,0 +1,125 @@
+package comb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func all(n, k int) (list []string) {
+	c := New(n, k)
+	for c.Next() {
+		list = append(list, fmt.Sprint(c.Indices()))
+	}
+	return
+}
+
+func TestNext(t *testing.T) {
+	got := fmt.Sprint(all(4, 2))
+	want := "[[0 1] [0 2] [0 3] [1 2] [1 3] [2 3]]"
+	if got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for n := 0; n <= 10; n++ {
+		for k := -1; k <= n+1; k++ {
+			list := all(n, k)
+			if len(list) != Count(n, k) {
+				t.Fatalf("C(%v,%v): got %v, want %v", n, k, len(list), Count(n, k))
+			}
+			seen := make(map[string]bool)
+			for _, s := range list {
+				if seen[s] {
+					t.Fatalf("C(%v,%v): %v twice", n, k, s)
+				}
+				seen[s] = true
+			}
+		}
+	}
+	// 取0个只有一种，空的
+	if got := all(3, 0); len(got) != 1 || got[0] != "[]" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestMask(t *testing.T) {
+	c := New(5, 3)
+	for c.Next() {
+		mask := c.Mask()
+		if len(mask) != 5 {
+			t.Fatalf("mask %v", mask)
+		}
+		n := 0
+		for i, b := range mask {
+			if b {
+				n++
+			}
+			in := false
+			for _, k := range c.Indices() {
+				in = in || k == i
+			}
+			if b != in {
+				t.Fatalf("mask %v indices %v", mask, c.Indices())
+			}
+		}
+		if n != 3 {
+			t.Fatalf("mask %v", mask)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// 以 0 开头的都不要
+	var list []string
+	c := New(4, 2)
+	for c.Next() {
+		if c.Indices()[0] == 0 {
+			c.Skip(0)
+			continue
+		}
+		list = append(list, fmt.Sprint(c.Indices()))
+	}
+	if got := fmt.Sprint(list); got != "[[1 2] [1 3] [2 3]]" {
+		t.Fatalf("got %v", got)
+	}
+
+	// 以 [0 1] 开头的跳过，别的都在
+	list = nil
+	c = New(4, 3)
+	for c.Next() {
+		list = append(list, fmt.Sprint(c.Indices()))
+		if c.Indices()[0] == 0 && c.Indices()[1] == 1 {
+			c.Skip(1)
+		}
+	}
+	if got := fmt.Sprint(list); got != "[[0 1 2] [0 2 3] [1 2 3]]" {
+		t.Fatalf("got %v", got)
+	}
+
+	// 最后一个跳过没有影响
+	c = New(3, 2)
+	n := 0
+	for c.Next() {
+		c.Skip(1)
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("got %v", n)
+	}
+}
+
+func TestCount(t *testing.T) {
+	for _, c := range []struct{ n, k, want int }{
+		{0, 0, 1}, {5, 0, 1}, {5, 5, 1}, {5, 2, 10}, {20, 10, 184756}, {3, 4, 0}, {3, -1, 0},
+	} {
+		if got := Count(c.n, c.k); got != c.want {
+			t.Fatalf("C(%v,%v) got %v, want %v", c.n, c.k, got, c.want)
+		}
+	}
+}
+
+func BenchmarkNext(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		c := New(20, 4)
+		for c.Next() {
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package device
+
+import (
+	"fmt"
+	"image"
+	"os/exec"
+	"time"
+)
+
+// Device 能在屏幕上点的东西，坐标都是截图上的像素
+type Device interface {
+	Tap(p image.Point) error       // 点一下，翻开格子
+	LongPress(p image.Point) error // 长按，插旗子
+	Back() error                   // 返回键，关掉弹窗
+}
+
+// ADB 用 adb shell input 点手机
+type ADB struct {
+	Serial string        // adb -s，空的话用唯一连着的设备
+	Hold   time.Duration // 长按多久，0 表示 600ms
+}
+
+func (a *ADB) Tap(p image.Point) error {
+	return a.shell("input", "tap", fmt.Sprint(p.X), fmt.Sprint(p.Y))
+}
+
+// LongPress 原地 swipe 就是长按
+func (a *ADB) LongPress(p image.Point) error {
+	hold := a.Hold
+	if hold == 0 {
+		hold = 600 * time.Millisecond
+	}
+	x, y := fmt.Sprint(p.X), fmt.Sprint(p.Y)
+	return a.shell("input", "swipe", x, y, x, y, fmt.Sprint(hold.Milliseconds()))
+}
+
+func (a *ADB) Back() error {
+	return a.shell("input", "keyevent", "KEYCODE_BACK")
+}
+
+func (a *ADB) shell(args ...string) error {
+	args = append([]string{"shell"}, args...)
+	if a.Serial != "" {
+		args = append([]string{"-s", a.Serial}, args...)
+	}
+	out, err := exec.Command("adb", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("adb %v: %w: %s", args, err, out)
+	}
+	return nil
+}
+
+// Nop 什么都不点，回放目录、视频里的截图时用，这时截图不会因为点了而变
+type Nop struct{}
+
+func (Nop) Tap(p image.Point) error       { return nil }
+func (Nop) LongPress(p image.Point) error { return nil }
+func (Nop) Back() error                   { return nil }
@@ -0,0 +1,264 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+
+	"taptap/biz/cell"
+	"taptap/biz/device"
+	"taptap/biz/view"
+)
+
+// ErrConflict boom 和 empty 里有同一个格子
+// view.Solve 遇到这种情况自己就返回 view.ErrContradiction 了，它的结果不会有这个错，
+// 这是给自己拼 boom 和 empty 的调用方检查用的，比如把几帧各自推出来的合在一起
+var ErrConflict = errors.New("plan: conflict")
+
+// Kind 要做的动作
+type Kind int
+
+const (
+	Open  Kind = iota // 点开格子
+	Flag              // 长按插旗子
+	Chord             // 点一个旗子已经插够了的数字，周围没开的格子一起翻开
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Open:
+		return "open"
+	case Flag:
+		return "flag"
+	case Chord:
+		return "chord"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Action 对一个格子做的动作，Chord 的格子是那个数字
+type Action struct {
+	Kind Kind
+	Cell *cell.Cell
+}
+
+func (a Action) String() string {
+	return fmt.Sprintf("%v %v", a.Kind, a.Cell)
+}
+
+// Order 点格子的顺序
+type Order int
+
+const (
+	OrderNearest Order = iota // 每次点离手指最近的，手指移动最少
+	OrderCascade              // 周围没开的格子多的先点，更容易连着开一大片
+)
+
+// Options 排动作的参数
+type Options struct {
+	Order  Order
+	Start  image.Point // 手指一开始在哪，截图上的像素
+	Chord  bool        // 一个数字周围能一次翻开两个以上的格子时，点数字代替一个个点
+	NoFlag bool        // 不插旗子，雷只记在程序里，省掉长按，这时 Chord 只认屏幕上已经有的旗子
+}
+
+// Plan 把推出来的雷和不是雷的格子整理成要做的动作
+// 同一个格子只做一次，已经开了的格子不再点，
+// 一个格子既是雷又不是雷的时候返回 ErrConflict，一个动作都不给
+// 先点开格子再插旗子，用 Chord 的时候要先插好旗子才能点数字，所以先插旗子
+// 屏幕上已经插了旗子的雷不会再插
+func Plan(v *view.View, boom, empty []*cell.Cell, opt Options) ([]Action, error) {
+	mines := dedup(boom, nil)
+	var conflict []*cell.Cell
+	for _, c := range dedup(empty, nil) {
+		if mines[c.Pt()] != nil {
+			conflict = append(conflict, c)
+		}
+	}
+	if len(conflict) > 0 {
+		sortCells(conflict)
+		return nil, fmt.Errorf("%w: %v 既是雷又不是雷", ErrConflict, conflict)
+	}
+
+	opens := dedup(empty, func(c *cell.Cell) bool { return c.IsUnknown() })
+	var taps, flags []Action
+	if !opt.NoFlag {
+		for _, c := range values(mines) {
+			if !c.IsFlag() {
+				flags = append(flags, Action{Flag, c})
+			}
+		}
+	}
+	if opt.Chord {
+		taps = chords(v, opens, mines, !opt.NoFlag)
+	}
+	for _, c := range values(opens) {
+		taps = append(taps, Action{Open, c})
+	}
+
+	if opt.Chord {
+		flags = nearest(flags, opt.Start)
+		taps = order(v, taps, mines, opt, last(flags, opt.Start))
+		return append(flags, taps...), nil
+	}
+	taps = order(v, taps, mines, opt, opt.Start)
+	flags = nearest(flags, last(taps, opt.Start))
+	return append(taps, flags...), nil
+}
+
+// chords 挑出能一次翻开两个以上格子的数字，翻开的格子从 opens 里去掉
+// 数字周围的雷都要推出来了，没开的格子也都要推出来不是雷，不然点了不知道会开出什么
+// 游戏只认屏幕上的旗子，flagging 为 false 时推出来的雷不算
+func chords(v *view.View, opens, mines map[cell.Point]*cell.Cell, flagging bool) (list []Action) {
+	for {
+		var best *cell.Cell
+		var bestOpen []*cell.Cell
+		for _, c := range values(numbers(v)) {
+			flag := 0
+			var open []*cell.Cell
+			ok := true
+			for _, n := range v.Around(c) {
+				switch {
+				case n.IsFlag() || flagging && mines[n.Pt()] != nil:
+					flag++
+				case !n.IsUnknown():
+				case opens[n.Pt()] != nil:
+					open = append(open, n)
+				default:
+					ok = false
+				}
+			}
+			if n, err := c.Num(); ok && err == nil && flag == n && len(open) >= 2 && len(open) > len(bestOpen) {
+				best, bestOpen = c, open
+			}
+		}
+		if best == nil {
+			return
+		}
+		list = append(list, Action{Chord, best})
+		for _, n := range bestOpen {
+			delete(opens, n.Pt())
+		}
+	}
+}
+
+func numbers(v *view.View) map[cell.Point]*cell.Cell {
+	ret := make(map[cell.Point]*cell.Cell)
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			if c := v.GetCell(i, j); c.IsNum() {
+				ret[c.Pt()] = c
+			}
+		}
+	}
+	return ret
+}
+
+func last(list []Action, start image.Point) image.Point {
+	if len(list) == 0 {
+		return start
+	}
+	return list[len(list)-1].Cell.Point()
+}
+
+// Run 让设备按顺序做完所有动作，出错就停下
+func Run(d device.Device, actions []Action) error {
+	for _, a := range actions {
+		var err error
+		switch a.Kind {
+		case Open:
+			err = d.Tap(a.Cell.Point())
+		case Flag:
+			err = d.LongPress(a.Cell.Point())
+		case Chord:
+			// 游戏里点翻开的数字就是 chord
+			err = d.Tap(a.Cell.Point())
+		default:
+			err = fmt.Errorf("plan: unknown action %v", a)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", a, err)
+		}
+	}
+	return nil
+}
+
+// dedup 按坐标去重，keep 不为 nil 时只留下 keep 返回 true 的
+func dedup(list []*cell.Cell, keep func(c *cell.Cell) bool) map[cell.Point]*cell.Cell {
+	ret := make(map[cell.Point]*cell.Cell)
+	for _, c := range list {
+		if keep == nil || keep(c) {
+			ret[c.Pt()] = c
+		}
+	}
+	return ret
+}
+
+func values(m map[cell.Point]*cell.Cell) (list []*cell.Cell) {
+	for _, c := range m {
+		list = append(list, c)
+	}
+	sortCells(list)
+	return
+}
+
+// sortCells 按行列排，map 出来的顺序不固定
+func sortCells(list []*cell.Cell) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].Pt(), list[j].Pt()
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+}
+
+// order 按 Options.Order 排点格子的顺序
+func order(v *view.View, list []Action, mines map[cell.Point]*cell.Cell, opt Options, start image.Point) []Action {
+	if opt.Order == OrderCascade {
+		return cascade(v, list, mines, start)
+	}
+	return nearest(list, start)
+}
+
+// nearest 从 start 开始每次走到最近的格子
+func nearest(list []Action, start image.Point) []Action {
+	ret := make([]Action, 0, len(list))
+	left := append([]Action(nil), list...)
+	at := start
+	for len(left) > 0 {
+		best := 0
+		for i, a := range left {
+			if dist(at, a.Cell.Point()) < dist(at, left[best].Cell.Point()) {
+				best = i
+			}
+		}
+		at = left[best].Cell.Point()
+		ret = append(ret, left[best])
+		left = append(left[:best], left[best+1:]...)
+	}
+	return ret
+}
+
+// cascade 周围没开、也不是雷的格子越多越先点，一样多的还是就近
+func cascade(v *view.View, list []Action, mines map[cell.Point]*cell.Cell, start image.Point) []Action {
+	score := make(map[cell.Point]int)
+	for _, a := range list {
+		for _, n := range v.Around(a.Cell) {
+			if n.IsUnknown() && mines[n.Pt()] == nil {
+				score[a.Cell.Pt()]++
+			}
+		}
+	}
+	list = nearest(list, start)
+	sort.SliceStable(list, func(i, j int) bool {
+		return score[list[i].Cell.Pt()] > score[list[j].Cell.Pt()]
+	})
+	return list
+}
+
+func dist(a, b image.Point) int {
+	d := a.Sub(b)
+	return d.X*d.X + d.Y*d.Y
+}
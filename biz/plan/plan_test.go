@@ -0,0 +1,188 @@
+package plan
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+type fakeDevice struct {
+	taps, presses []image.Point
+}
+
+func (d *fakeDevice) Tap(p image.Point) error {
+	d.taps = append(d.taps, p)
+	return nil
+}
+
+func (d *fakeDevice) LongPress(p image.Point) error {
+	d.presses = append(d.presses, p)
+	return nil
+}
+
+func (d *fakeDevice) Back() error {
+	return nil
+}
+
+func cells(v *view.View, pts ...cell.Point) (list []*cell.Cell) {
+	for _, p := range pts {
+		list = append(list, v.GetCell(p.X, p.Y))
+	}
+	return
+}
+
+func TestPlan(t *testing.T) {
+	v, err := view.Parse(`
+		____
+		_1__
+		____
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重复的、已经开了的都去掉，先开格子再插旗子
+	boom := cells(v, cell.Pt(0, 3), cell.Pt(0, 3))
+	empty := cells(v, cell.Pt(2, 3), cell.Pt(0, 0), cell.Pt(1, 1), cell.Pt(0, 0), cell.Pt(0, 1))
+	actions, err := Plan(v, boom, empty, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Action{
+		{Open, v.GetCell(0, 0)},
+		{Open, v.GetCell(0, 1)},
+		{Open, v.GetCell(2, 3)},
+		{Flag, v.GetCell(0, 3)},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("got %v", actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("got %v, want %v", actions, want)
+		}
+	}
+
+	d := &fakeDevice{}
+	if err := Run(d, actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.taps) != 3 || len(d.presses) != 1 || d.presses[0] != v.GetCell(0, 3).Point() {
+		t.Fatalf("got taps %v presses %v", d.taps, d.presses)
+	}
+}
+
+func TestPlanCascade(t *testing.T) {
+	v, err := view.Parse(`
+		1___
+		____
+		____
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0,1 旁边有4个没开的，2,2 旁边有5个，先点 2,2
+	empty := cells(v, cell.Pt(0, 1), cell.Pt(2, 2))
+	actions, err := Plan(v, nil, empty, Options{Order: OrderCascade})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Cell.Pt() != cell.Pt(2, 2) {
+		t.Fatalf("got %v", actions)
+	}
+	actions, _ = Plan(v, nil, empty, Options{})
+	if actions[0].Cell.Pt() != cell.Pt(0, 1) {
+		t.Fatalf("got %v", actions)
+	}
+}
+
+func TestPlanConflict(t *testing.T) {
+	v, err := view.Parse(`
+		1_
+		__
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Plan(v, cells(v, cell.Pt(1, 1)), cells(v, cell.Pt(0, 1), cell.Pt(1, 1)), Options{})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("want conflict, got %v", err)
+	}
+}
+
+func TestPlanChord(t *testing.T) {
+	v, err := view.Parse(`
+		f1___
+		11___
+		_____
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1,1 周围的雷只有 0,0，插着旗子，剩下的都不是雷，点一下 1,1 全开
+	// 2,4 一个格子就不用 chord，插完 0,4 的旗子离它更近，先点它
+	empty := cells(v, cell.Pt(0, 2), cell.Pt(1, 2), cell.Pt(2, 0), cell.Pt(2, 1), cell.Pt(2, 2), cell.Pt(2, 4))
+	boom := cells(v, cell.Pt(0, 4))
+	actions, err := Plan(v, boom, empty, Options{Chord: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Action{
+		{Flag, v.GetCell(0, 4)},
+		{Open, v.GetCell(2, 4)},
+		{Chord, v.GetCell(1, 1)},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("got %v", actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("got %v, want %v", actions, want)
+		}
+	}
+
+	d := &fakeDevice{}
+	if err := Run(d, actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.taps) != 2 || d.taps[1] != v.GetCell(1, 1).Point() {
+		t.Fatalf("got taps %v", d.taps)
+	}
+}
+
+func TestPlanNoFlag(t *testing.T) {
+	v, err := view.Parse(`
+		f1_m
+		11_1
+		____
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0,3 是推出来的雷，不插旗子的话 1,3 周围的旗子不够，不能 chord
+	boom := cells(v, cell.Pt(0, 0), cell.Pt(0, 3))
+	empty := cells(v, cell.Pt(0, 2), cell.Pt(1, 2), cell.Pt(2, 0), cell.Pt(2, 1), cell.Pt(2, 2), cell.Pt(2, 3))
+	actions, err := Plan(v, boom, empty, Options{Chord: true, NoFlag: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range actions {
+		if a.Kind == Flag {
+			t.Fatalf("got %v", actions)
+		}
+	}
+	if len(actions) != 2 || actions[0] != (Action{Chord, v.GetCell(1, 1)}) {
+		t.Fatalf("got %v", actions)
+	}
+
+	// 插旗子的话 0,0 已经插了，只插 0,3
+	actions, err = Plan(v, boom, empty, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := actions[len(actions)-1]; a != (Action{Flag, v.GetCell(0, 3)}) || actions[len(actions)-2].Kind == Flag {
+		t.Fatalf("got %v", actions)
+	}
+}
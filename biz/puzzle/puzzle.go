@@ -0,0 +1,202 @@
+// Package puzzle 检查一道扫雷题能不能不靠猜一路推到底
+package puzzle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+// maxEnum 出题的棋盘不大，穷举放宽一点，尽量用完整的推理
+const maxEnum = 40
+
+// ErrWrong 规则推出来的和答案对不上，说明规则有问题
+var ErrWrong = errors.New("puzzle: deduction does not match the answer")
+
+// Puzzle 一道题：所有的雷都知道，一开始翻开了一些数字
+type Puzzle struct {
+	answer []bool // 是不是雷
+	nums   []int  // 每个格子的数字
+	start  string // 一开始给玩家看的棋盘，和 view.Parse 的格式一样
+	mines  int
+	topo   view.Topology
+}
+
+/*
+Parse 从文本得到一道题，topo 是 nil 时是普通扫雷，每个字符是：
+
+	0-8  一开始就翻开的数字，要和周围的雷对得上
+	_    不是雷，但是没开，要玩家推出来
+	*    雷，玩家看到的是没开的格子
+*/
+func Parse(text string, topo view.Topology) (*Puzzle, error) {
+	v, err := view.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	v.SetTopology(topo)
+	p := &Puzzle{topo: topo}
+	var b strings.Builder
+	for i := 0; i < v.Rows()*v.Cols(); i++ {
+		c := v.GetCell(i/v.Cols(), i%v.Cols())
+		switch {
+		case c.State() == cell.Mine:
+			p.answer = append(p.answer, true)
+			p.mines++
+			b.WriteByte('_')
+		case c.IsNum(), c.IsUnknown():
+			p.answer = append(p.answer, false)
+			b.WriteByte(c.Byte())
+		default:
+			return nil, fmt.Errorf("puzzle: %v: want mine, number or unknown", c)
+		}
+		if (i+1)%v.Cols() == 0 {
+			b.WriteByte('\n')
+		}
+	}
+	p.start = b.String()
+	for i := range p.answer {
+		c := v.GetCell(i/v.Cols(), i%v.Cols())
+		n := 0
+		for _, one := range v.Around(c) {
+			if p.answer[one.Index(v.Cols())] {
+				n++
+			}
+		}
+		p.nums = append(p.nums, n)
+		if num, err := c.Num(); err == nil && num != n {
+			return nil, fmt.Errorf("puzzle: %v has %v mines around", c, n)
+		}
+	}
+	return p, nil
+}
+
+// Step 一轮推出来的，推出来不是雷的格子这一轮结束后翻开
+type Step struct {
+	Mines []view.Deduction
+	Safe  []view.Deduction
+}
+
+// Report 检查的结果
+type Report struct {
+	Steps  []Step
+	Solved bool         // 所有不是雷的格子都推出来了
+	Stuck  []cell.Point // 推不下去的时候，还没推出来的不是雷的格子
+	Board  string       // 推不下去的时候玩家看到的棋盘，推出来的雷标成 m
+	// Skipped 推不下去的时候，一组太大没有穷举的格子，不是空的话说明可能不用猜，只是没算
+	Skipped []cell.Point
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for i, st := range r.Steps {
+		fmt.Fprintf(&b, "step %v\n", i+1)
+		for _, d := range st.Mines {
+			fmt.Fprintf(&b, "  %v\n", d)
+		}
+		for _, d := range st.Safe {
+			fmt.Fprintf(&b, "  %v\n", d)
+		}
+	}
+	if r.Solved {
+		b.WriteString("solved without guessing\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "stuck after %v steps, %v safe cells left: %v\n%v", len(r.Steps), len(r.Stuck), r.Stuck, r.Board)
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(&b, "enumeration skipped %v cells in groups over %v cells: %v\n", len(r.Skipped), maxEnum, r.Skipped)
+	}
+	return b.String()
+}
+
+// Check 从一开始的棋盘推，推出来不是雷的就翻开，直到推完或者推不下去
+func (p *Puzzle) Check() (*Report, error) {
+	return p.check(p.start, true)
+}
+
+// Suggest 推不下去的时候，一个一个多翻开格子，每次挑翻开以后剩下的最少的，直到能推完
+// 返回要多翻开的格子，本来就能推完的返回 nil
+func (p *Puzzle) Suggest() ([]cell.Point, error) {
+	var list []cell.Point
+	board := p.start
+	for {
+		r, err := p.check(board, false)
+		if err != nil {
+			return nil, err
+		}
+		if r.Solved {
+			return list, nil
+		}
+		best, bestLeft := -1, len(r.Stuck)+1
+		for k, pt := range r.Stuck {
+			next, err := p.check(p.reveal(r.Board, pt), false)
+			if err != nil {
+				return nil, err
+			}
+			if len(next.Stuck) < bestLeft {
+				best, bestLeft = k, len(next.Stuck)
+			}
+		}
+		list = append(list, r.Stuck[best])
+		board = p.reveal(r.Board, r.Stuck[best])
+	}
+}
+
+// check record 为 false 时不记每一步，Suggest 里要试很多次
+func (p *Puzzle) check(board string, record bool) (*Report, error) {
+	v, err := view.Parse(board)
+	if err != nil {
+		return nil, err
+	}
+	v.SetTopology(p.topo)
+	r := &Report{}
+	var skipped []*cell.Cell
+	for {
+		ret, err := view.Solve(v, view.Options{Mines: p.mines, MaxEnum: maxEnum, InPlace: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, list := range [][]view.Deduction{ret.Mines, ret.Safe} {
+			for _, d := range list {
+				if d.Mine != p.answer[d.Cell.Index(v.Cols())] {
+					return nil, fmt.Errorf("%w: %v", ErrWrong, d)
+				}
+			}
+		}
+		skipped = ret.Skipped
+		if record && len(ret.Mines)+len(ret.Safe) > 0 {
+			r.Steps = append(r.Steps, Step{Mines: ret.Mines, Safe: ret.Safe})
+		}
+		if len(ret.Safe) == 0 {
+			break
+		}
+		for _, d := range ret.Safe {
+			v.SetState(d.Cell, cell.State(p.nums[d.Cell.Index(v.Cols())]))
+		}
+	}
+	for i, mine := range p.answer {
+		if c := v.GetCell(i/v.Cols(), i%v.Cols()); !mine && c.IsUnknown() {
+			r.Stuck = append(r.Stuck, c.Pt())
+		}
+	}
+	r.Solved = len(r.Stuck) == 0
+	if !r.Solved {
+		r.Board = v.String()
+		for _, c := range skipped {
+			r.Skipped = append(r.Skipped, c.Pt())
+		}
+	}
+	return r, nil
+}
+
+// reveal 在棋盘文本上把 pt 翻开
+func (p *Puzzle) reveal(board string, pt cell.Point) string {
+	lines := strings.Split(strings.TrimSpace(board), "\n")
+	line := []byte(lines[pt.X])
+	line[pt.Y] = cell.State(p.nums[cell.Index(pt.X, pt.Y, len(line))]).Byte()
+	lines[pt.X] = string(line)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+package puzzle
+
+import (
+	"strings"
+	"testing"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := Parse(`
+		*__
+		___
+		__0
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Solved || len(r.Steps) == 0 || len(r.Stuck) != 0 {
+		t.Fatalf("got\n%v", r)
+	}
+	last := r.Steps[len(r.Steps)-1]
+	if len(last.Mines) != 1 || last.Mines[0].Cell.Pt() != cell.Pt(0, 0) {
+		t.Fatalf("got\n%v", r)
+	}
+	if list, err := p.Suggest(); err != nil || list != nil {
+		t.Fatalf("got %v %v", list, err)
+	}
+}
+
+func TestCheckStuck(t *testing.T) {
+	p, err := Parse(`
+		*_
+		11
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Solved || len(r.Stuck) != 1 || r.Stuck[0] != cell.Pt(0, 1) || r.Board != "__\n11\n" || r.Skipped != nil {
+		t.Fatalf("got\n%v", r)
+	}
+	if !strings.Contains(r.String(), "stuck") {
+		t.Fatalf("got\n%v", r)
+	}
+	list, err := p.Suggest()
+	if err != nil || len(list) != 1 || list[0] != cell.Pt(0, 1) {
+		t.Fatalf("got %v %v", list, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, text := range []string{"*0", "*f", "*2\n__"} {
+		if _, err := Parse(text, nil); err == nil {
+			t.Errorf("%q: want error", text)
+		}
+	}
+	// 环面上角上的雷挨着对角
+	if _, err := Parse("*0\n01", view.Torus{}); err == nil {
+		t.Error("torus: want error")
+	}
+	if _, err := Parse("*1\n11", view.Torus{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package report 把一次识别和求解的结果整理成固定格式的 json，给别的程序用
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+// Version 格式变了就加一，读的程序先看这个
+const Version = 1
+
+/*
+Report 一帧的结果，写成 json 是这样的：
+
+	{
+	  "version": 1,
+	  "grid": {"rows": 2, "cols": 2, "row_lines": [100, 150, 200], "col_lines": [50, 100, 150], "row_pitch": 50, "col_pitch": 50},
+	  "cells": [{"row": 0, "col": 0, "state": "1", "x": 75, "y": 125, "confidence": 0.93}, ...],
+	  "mines": [{"row": 0, "col": 1, "count": 1, "rule": "boom", "depth": 1}],
+	  "safe": [],
+	  "negative": [],
+	  "timing_ms": {"recognize": 120.5, "solve": 1.2}
+	}
+
+state 和 view.Parse 用的字符一样，数字是 "0"-"8"，没开的是 "_"
+*/
+type Report struct {
+	Version  int                `json:"version"`
+	Grid     Grid               `json:"grid"`
+	Cells    []Cell             `json:"cells"` // 一行一行排
+	Mines    []Deduction        `json:"mines"`
+	Safe     []Deduction        `json:"safe"`
+	Negative []Deduction        `json:"negative"`        // 变种里雷数是负数的格子，不是雷，也不能点
+	Error    string             `json:"error,omitempty"` // 求解出错，比如发现矛盾，mines 和 safe 是出错之前推出来的
+	Timing   map[string]float64 `json:"timing_ms"`       // 每一步花了多少毫秒
+}
+
+// Grid 网格线在截图上的位置
+type Grid struct {
+	Rows     int     `json:"rows"`
+	Cols     int     `json:"cols"`
+	RowLines []int   `json:"row_lines"` // 横线的 y，比 rows 多一个
+	ColLines []int   `json:"col_lines"` // 竖线的 x，比 cols 多一个
+	RowPitch float64 `json:"row_pitch"` // 平均行高
+	ColPitch float64 `json:"col_pitch"` // 平均列宽
+}
+
+// Cell 识别出来的一个格子
+type Cell struct {
+	Row        int        `json:"row"`
+	Col        int        `json:"col"`
+	State      cell.State `json:"state"`
+	Num        *int       `json:"num,omitempty"` // 变种扫雷里 0-8 以外的数字，state 是 "o"
+	X          int        `json:"x"`             // 小图中心在截图上的位置
+	Y          int        `json:"y"`
+	Confidence *float64   `json:"confidence,omitempty"` // 识别的把握，0-1，分类器给不出来就没有
+}
+
+// Deduction 推出来的一个格子
+type Deduction struct {
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Count   int    `json:"count"`             // 有几个雷，不是雷是0，negative 里是负数
+	Rule    string `json:"rule"`              // 哪条规则，和 view.Rule 的名字一样
+	Pattern string `json:"pattern,omitempty"` // rule 是 pattern 时是哪个模式
+	Depth   int    `json:"depth"`             // 第几轮推出来的
+}
+
+// New 从识别出来的棋盘生成，rowLines 和 colLines 是 getGrid 找到的网格线
+func New(v *view.View, rowLines, colLines []int) *Report {
+	r := &Report{
+		Version: Version,
+		Grid: Grid{
+			Rows:     v.Rows(),
+			Cols:     v.Cols(),
+			RowLines: rowLines,
+			ColLines: colLines,
+			RowPitch: pitch(rowLines),
+			ColPitch: pitch(colLines),
+		},
+		Cells:    []Cell{},
+		Mines:    []Deduction{},
+		Safe:     []Deduction{},
+		Negative: []Deduction{},
+		Timing:   map[string]float64{},
+	}
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			c := v.GetCell(i, j)
+			one := Cell{Row: i, Col: j, State: c.State(), X: c.Point().X, Y: c.Point().Y}
+			if n, ok := v.Num(c); ok && !c.IsNum() {
+				one.Num = &n
+			}
+			r.Cells = append(r.Cells, one)
+		}
+	}
+	return r
+}
+
+// Solved 写上 view.Solve 的结果
+func (r *Report) Solved(ret *view.Result, err error) {
+	if ret != nil {
+		r.Mines = deductions(ret.Mines)
+		r.Safe = deductions(ret.Safe)
+		r.Negative = deductions(ret.Negative)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Score 给每个格子写上识别的把握，score 返回 false 的不写
+func (r *Report) Score(v *view.View, score func(c *cell.Cell) (float64, bool)) {
+	for k := range r.Cells {
+		one := &r.Cells[k]
+		if s, ok := score(v.GetCell(one.Row, one.Col)); ok {
+			one.Confidence = &s
+		}
+	}
+}
+
+// Time 记下一步花的时间
+func (r *Report) Time(name string, d time.Duration) {
+	r.Timing[name] = float64(d.Microseconds()) / 1000
+}
+
+// Write 写成一行 json，一帧一行，方便别的程序一行一行读
+func (r *Report) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+func deductions(list []view.Deduction) []Deduction {
+	ret := []Deduction{}
+	for _, d := range list {
+		ret = append(ret, Deduction{
+			Row:     d.Cell.Pt().X,
+			Col:     d.Cell.Pt().Y,
+			Count:   d.Count,
+			Rule:    d.Rule.String(),
+			Pattern: d.Pattern,
+			Depth:   d.Depth,
+		})
+	}
+	return ret
+}
+
+// pitch 相邻两条线的平均距离
+func pitch(lines []int) float64 {
+	if len(lines) < 2 {
+		return 0
+	}
+	return float64(lines[len(lines)-1]-lines[0]) / float64(len(lines)-1)
+}
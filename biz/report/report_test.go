@@ -0,0 +1,73 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+func TestReport(t *testing.T) {
+	v, err := view.Parse(`
+		1_
+		11
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(v, []int{100, 150, 200}, []int{50, 100, 150})
+	ret, err := view.Solve(v, view.Options{})
+	r.Solved(ret, err)
+	r.Score(v, func(c *cell.Cell) (float64, bool) {
+		return 0.5, c.IsNum()
+	})
+	r.Time("solve", 1500*time.Microsecond)
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != Version || got.Grid.Rows != 2 || got.Grid.RowPitch != 50 || len(got.Cells) != 4 {
+		t.Fatalf("got %+v", got)
+	}
+	if got.Cells[1].State != cell.Unknown || got.Cells[1].Confidence != nil || *got.Cells[0].Confidence != 0.5 {
+		t.Fatalf("cells %+v", got.Cells)
+	}
+	if len(got.Mines) != 1 || got.Mines[0] != (Deduction{Row: 0, Col: 1, Count: 1, Rule: "boom", Depth: 1}) {
+		t.Fatalf("mines %+v", got.Mines)
+	}
+	if got.Safe == nil || got.Timing["solve"] != 1.5 || got.Error != "" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestReportError(t *testing.T) {
+	v, _ := view.Parse("[12]_")
+	r := New(v, nil, nil)
+	if r.Cells[0].Num == nil || *r.Cells[0].Num != 12 || r.Grid.RowPitch != 0 {
+		t.Fatalf("got %+v", r)
+	}
+	r.Solved(&view.Result{}, view.ErrContradiction)
+	if r.Error != view.ErrContradiction.Error() {
+		t.Fatalf("got %q", r.Error)
+	}
+}
+
+func TestReportNegative(t *testing.T) {
+	v, _ := view.Parse("_[-1]")
+	r := New(v, nil, nil)
+	r.Solved(&view.Result{Negative: []view.Deduction{{Cell: v.GetCell(0, 0), Count: -1, Rule: view.RuleEnum, Depth: 1}}}, nil)
+	if len(r.Negative) != 1 || r.Negative[0] != (Deduction{Row: 0, Col: 0, Count: -1, Rule: "enum", Depth: 1}) {
+		t.Fatalf("negative %+v", r.Negative)
+	}
+	if len(r.Mines) != 0 || len(r.Safe) != 0 {
+		t.Fatalf("got %+v", r)
+	}
+}
@@ -0,0 +1,250 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+// Game 一局扫雷，雷在第一次翻开的时候才放，第一下和它周围一定不是雷
+type Game struct {
+	rows, cols int
+	mines      int
+	rnd        *rand.Rand
+	placed     bool   // 雷放好了没有
+	mine       []bool // 这个格子是雷
+	open       []bool // 这个格子翻开了
+	flag       []bool // 这个格子插了旗子
+	opened     int    // 翻开了几个格子
+	exploded   int    // 踩到的雷，没踩到是 -1
+}
+
+// NewGame 同样的 seed 和同样的第一下，雷的位置一样
+func NewGame(rows, cols, mines int, seed int64) *Game {
+	if mines > rows*cols-1 {
+		mines = rows*cols - 1
+	}
+	return &Game{
+		rows:     rows,
+		cols:     cols,
+		mines:    mines,
+		rnd:      rand.New(rand.NewSource(seed)),
+		mine:     make([]bool, rows*cols),
+		open:     make([]bool, rows*cols),
+		flag:     make([]bool, rows*cols),
+		exploded: -1,
+	}
+}
+
+func (g *Game) Rows() int {
+	return g.rows
+}
+
+func (g *Game) Cols() int {
+	return g.cols
+}
+
+func (g *Game) Mines() int {
+	return g.mines
+}
+
+// Opened 翻开了几个格子
+func (g *Game) Opened() int {
+	return g.opened
+}
+
+// Flags 插了几个旗子
+func (g *Game) Flags() (n int) {
+	for _, f := range g.flag {
+		if f {
+			n++
+		}
+	}
+	return
+}
+
+func (g *Game) Lost() bool {
+	return g.exploded >= 0
+}
+
+// Won 不是雷的格子都翻开了
+func (g *Game) Won() bool {
+	return !g.Lost() && g.opened == g.rows*g.cols-g.mines
+}
+
+func (g *Game) over() bool {
+	return g.Lost() || g.Won()
+}
+
+func (g *Game) in(row, col int) bool {
+	return 0 <= row && row < g.rows && 0 <= col && col < g.cols
+}
+
+// around 周围8个格子的下标
+func (g *Game) around(row, col int) (list []int) {
+	for _, p := range cell.Pt(row, col).GetSub() {
+		if (p.X != row || p.Y != col) && g.in(p.X, p.Y) {
+			list = append(list, cell.Index(p.X, p.Y, g.cols))
+		}
+	}
+	return
+}
+
+// place 放雷，first 和它周围不放，格子不够的话只避开 first
+func (g *Game) place(row, col int) {
+	first := cell.Index(row, col, g.cols)
+	avoid := map[int]bool{first: true}
+	if g.rows*g.cols-g.mines >= 9 {
+		for _, k := range g.around(row, col) {
+			avoid[k] = true
+		}
+	}
+	var list []int
+	for k := range g.mine {
+		if !avoid[k] {
+			list = append(list, k)
+		}
+	}
+	g.rnd.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	for _, k := range list[:g.mines] {
+		g.mine[k] = true
+	}
+	g.placed = true
+}
+
+// count 周围有几个雷
+func (g *Game) count(row, col int) (n int) {
+	for _, k := range g.around(row, col) {
+		if g.mine[k] {
+			n++
+		}
+	}
+	return
+}
+
+// Open 翻开一个格子，是0的话把周围的也翻开，插了旗子的不会翻开
+func (g *Game) Open(row, col int) {
+	if !g.in(row, col) || g.over() {
+		return
+	}
+	if !g.placed {
+		g.place(row, col)
+	}
+	k := cell.Index(row, col, g.cols)
+	if g.open[k] || g.flag[k] {
+		return
+	}
+	if g.mine[k] {
+		g.exploded = k
+		return
+	}
+	list := []int{k}
+	g.open[k] = true
+	g.opened++
+	for len(list) > 0 {
+		k, list = list[0], list[1:]
+		r, c := k/g.cols, k%g.cols
+		if g.count(r, c) > 0 {
+			continue
+		}
+		for _, n := range g.around(r, c) {
+			if !g.open[n] && !g.flag[n] {
+				g.open[n] = true
+				g.opened++
+				list = append(list, n)
+			}
+		}
+	}
+}
+
+// Flag 插旗子，再来一次就拔掉
+func (g *Game) Flag(row, col int) {
+	if !g.in(row, col) || g.over() {
+		return
+	}
+	k := cell.Index(row, col, g.cols)
+	if !g.open[k] {
+		g.flag[k] = !g.flag[k]
+	}
+}
+
+// Chord 翻开的数字周围的旗子够了，就把周围别的格子都翻开，旗子插错了会踩雷
+func (g *Game) Chord(row, col int) {
+	if !g.in(row, col) || g.over() {
+		return
+	}
+	k := cell.Index(row, col, g.cols)
+	if !g.open[k] {
+		return
+	}
+	around := g.around(row, col)
+	flags := 0
+	for _, n := range around {
+		if g.flag[n] {
+			flags++
+		}
+	}
+	if flags != g.count(row, col) {
+		return
+	}
+	for _, n := range around {
+		g.Open(n/g.cols, n%g.cols)
+	}
+}
+
+// State 玩家看到的格子
+func (g *Game) State(row, col int) cell.State {
+	k := cell.Index(row, col, g.cols)
+	switch {
+	case g.open[k]:
+		return cell.State(g.count(row, col))
+	case k == g.exploded:
+		return cell.Exploded
+	case g.flag[k] && g.Lost() && !g.mine[k]:
+		return cell.WrongFlag
+	case g.flag[k]:
+		return cell.Flag
+	case g.mine[k] && g.Lost():
+		return cell.Mine
+	}
+	return cell.Unknown
+}
+
+// View 玩家看到的棋盘，和 view.Parse 出来的一样没有小图
+func (g *Game) View() *view.View {
+	v, _ := view.Parse(g.String())
+	return v
+}
+
+// String 玩家看到的棋盘，和 view.Parse 用的格式一样
+func (g *Game) String() string {
+	var b strings.Builder
+	for i := 0; i < g.rows; i++ {
+		for j := 0; j < g.cols; j++ {
+			b.WriteByte(g.State(i, j).Byte())
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Answer 雷的位置，* 是雷，调试用
+func (g *Game) Answer() string {
+	var b strings.Builder
+	for i := 0; i < g.rows; i++ {
+		for j := 0; j < g.cols; j++ {
+			if g.mine[cell.Index(i, j, g.cols)] {
+				b.WriteByte('*')
+			} else {
+				fmt.Fprint(&b, g.count(i, j))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
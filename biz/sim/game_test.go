@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+
+	"taptap/biz/cell"
+)
+
+func TestGameFirstSafe(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		g := NewGame(9, 9, 10, seed)
+		g.Open(4, 4)
+		if g.Lost() || g.State(4, 4) != cell.State(0) {
+			t.Fatalf("seed %v: first open got %v\n%v", seed, g.State(4, 4), g.Answer())
+		}
+		if g.Opened() < 9 {
+			t.Fatalf("seed %v: opened %v", seed, g.Opened())
+		}
+		if n := strings.Count(g.Answer(), "*"); n != 10 {
+			t.Fatalf("seed %v: %v mines", seed, n)
+		}
+	}
+	a, b := NewGame(9, 9, 10, 7), NewGame(9, 9, 10, 7)
+	a.Open(0, 0)
+	b.Open(0, 0)
+	if a.Answer() != b.Answer() || a.String() != b.String() {
+		t.Fatal("same seed got different boards")
+	}
+}
+
+// mineAt 找一个雷和一个不是雷的没开的格子
+func mineAt(g *Game) (mine, safe cell.Point) {
+	for i := 0; i < g.rows; i++ {
+		for j := 0; j < g.cols; j++ {
+			k := cell.Index(i, j, g.cols)
+			if g.mine[k] {
+				mine = cell.Pt(i, j)
+			} else if !g.open[k] {
+				safe = cell.Pt(i, j)
+			}
+		}
+	}
+	return
+}
+
+func TestGameFlagChord(t *testing.T) {
+	g := NewGame(9, 9, 10, 1)
+	g.Open(4, 4)
+	mine, _ := mineAt(g)
+
+	// 插了旗子的格子点不开
+	g.Flag(mine.X, mine.Y)
+	g.Open(mine.X, mine.Y)
+	if g.Lost() || g.State(mine.X, mine.Y) != cell.Flag || g.Flags() != 1 {
+		t.Fatalf("got %v", g.State(mine.X, mine.Y))
+	}
+	g.Flag(mine.X, mine.Y)
+	if g.Flags() != 0 {
+		t.Fatal("flag not removed")
+	}
+
+	// 把所有的雷都插上旗子，随便 chord 一个数字都不会输，一直 chord 到赢
+	for i := 0; i < g.rows; i++ {
+		for j := 0; j < g.cols; j++ {
+			if g.mine[cell.Index(i, j, g.cols)] {
+				g.Flag(i, j)
+			}
+		}
+	}
+	for n := 0; n < g.rows*g.cols && !g.Won(); n++ {
+		for i := 0; i < g.rows; i++ {
+			for j := 0; j < g.cols; j++ {
+				if g.State(i, j).IsNum() {
+					g.Chord(i, j)
+				}
+			}
+		}
+	}
+	if !g.Won() {
+		t.Fatalf("not won\n%v", g)
+	}
+}
+
+func TestGameLost(t *testing.T) {
+	g := NewGame(9, 9, 10, 2)
+	g.Open(4, 4)
+	mine, safe := mineAt(g)
+	g.Flag(safe.X, safe.Y)
+	g.Open(mine.X, mine.Y)
+	if !g.Lost() || g.Won() {
+		t.Fatal("want lost")
+	}
+	if g.State(mine.X, mine.Y) != cell.Exploded || g.State(safe.X, safe.Y) != cell.WrongFlag {
+		t.Fatalf("got\n%v", g)
+	}
+	if !strings.Contains(g.String(), "*") {
+		t.Fatalf("mines not shown\n%v", g)
+	}
+	before := g.String()
+	g.Open(0, 0)
+	if g.String() != before {
+		t.Fatal("board changed after lost")
+	}
+	if v := g.View(); v.Rows() != 9 || v.Cols() != 9 {
+		t.Fatalf("view %vx%v", v.Rows(), v.Cols())
+	}
+}
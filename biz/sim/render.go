@@ -0,0 +1,124 @@
+package sim
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"taptap/biz/cell"
+	"taptap/img"
+
+	"gocv.io/x/gocv"
+)
+
+// Layout 模拟截图的大小和棋盘的位置，和手机上一样是 720*1600
+// get_y_list 要求竖线长度至少是截图宽度的55%，720 宽就是 396 像素，一格 53 像素的话棋盘要有 8 行以上才认得出来
+type Layout struct {
+	Width, Height int
+	Top, Left     int // 棋盘左上角
+	Pitch         int // 一格多少像素，格子之间的线一格各占一半
+}
+
+// NewLayout 一格和手机上一样 53 像素，放不下就缩小，棋盘放在上面 200 像素以下
+func NewLayout(rows, cols int) Layout {
+	l := Layout{Width: 720, Height: 1600, Top: 220, Left: 30, Pitch: 53}
+	if p := (700 - l.Left) / cols; p < l.Pitch {
+		l.Pitch = p
+	}
+	if p := (l.Height - img.BoardBottom - l.Top) / rows; p < l.Pitch {
+		l.Pitch = p
+	}
+	return l
+}
+
+// Rect 格子在截图上的位置
+func (l Layout) Rect(row, col int) image.Rectangle {
+	x := l.Left + col*l.Pitch
+	y := l.Top + row*l.Pitch
+	return image.Rect(x, y, x+l.Pitch, y+l.Pitch)
+}
+
+// Center 格子的中心，点这里
+func (l Layout) Center(row, col int) image.Point {
+	r := l.Rect(row, col)
+	return image.Pt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+}
+
+// At 截图上的点在哪个格子里
+func (l Layout) At(p image.Point, rows, cols int) (row, col int, ok bool) {
+	if p.X < l.Left || p.Y < l.Top {
+		return 0, 0, false
+	}
+	row = (p.Y - l.Top) / l.Pitch
+	col = (p.X - l.Left) / l.Pitch
+	return row, col, row < rows && col < cols
+}
+
+// Templates 每种格子的模板小图，用 tar 目录里的
+type Templates map[cell.State]gocv.Mat
+
+// LoadTemplates 读 tar 目录，同一种格子有几张的用第一张
+func LoadTemplates(dir string) (Templates, error) {
+	t := make(Templates)
+	for _, f := range img.TarFiles {
+		label, err := img.LabelByName(f.Label)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		if _, ok := t[label.State]; ok {
+			continue
+		}
+		name := fmt.Sprintf("%v/tar%v.png", dir, f.Name)
+		src := gocv.IMRead(name, gocv.IMReadColor)
+		if src.Empty() {
+			src.Close()
+			t.Close()
+			return nil, fmt.Errorf("sim: read %v", name)
+		}
+		t[label.State] = src
+	}
+	return t, nil
+}
+
+func (t Templates) Close() {
+	for _, m := range t {
+		m.Close()
+	}
+}
+
+var (
+	background = gocv.NewScalar(40, 40, 40, 0)
+	lineColor  = color.RGBA{230, 230, 230, 0}
+)
+
+// Render 画出玩家现在看到的截图，调用的人负责 Close
+// 格子用模板缩放到一格大小拼起来，再在格子之间画线
+func Render(g *Game, l Layout, t Templates) gocv.Mat {
+	dst := gocv.NewMatWithSizeFromScalar(background, l.Height, l.Width, gocv.MatTypeCV8UC3)
+	for i := 0; i < g.rows; i++ {
+		for j := 0; j < g.cols; j++ {
+			drawCell(&dst, l.Rect(i, j), g.State(i, j), t)
+		}
+	}
+	board := image.Rectangle{l.Rect(0, 0).Min, l.Rect(g.rows-1, g.cols-1).Max}
+	for i := 0; i <= g.rows; i++ {
+		y := board.Min.Y + i*l.Pitch
+		gocv.Line(&dst, image.Pt(board.Min.X, y), image.Pt(board.Max.X, y), lineColor, 2)
+	}
+	for j := 0; j <= g.cols; j++ {
+		x := board.Min.X + j*l.Pitch
+		gocv.Line(&dst, image.Pt(x, board.Min.Y), image.Pt(x, board.Max.Y), lineColor, 2)
+	}
+	return dst
+}
+
+// drawCell 模板缩放到一格大小贴上去
+func drawCell(dst *gocv.Mat, rect image.Rectangle, state cell.State, t Templates) {
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.Resize(t[state], &small, rect.Size(), 0, 0, gocv.InterpolationArea)
+	region := dst.Region(rect)
+	defer region.Close()
+	small.CopyTo(&region)
+}
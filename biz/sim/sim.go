@@ -0,0 +1,59 @@
+package sim
+
+import (
+	"image"
+
+	"taptap/biz/device"
+
+	"gocv.io/x/gocv"
+)
+
+var _ device.Device = (*Sim)(nil)
+
+// Sim 不用手机，在电脑上玩：Screencap 出截图，Tap/LongPress 和手机上一样点
+type Sim struct {
+	*Game
+	Layout
+	tpl Templates
+}
+
+// New 模板归调用的人管，Sim 用完模板再 Close
+func New(g *Game, t Templates) *Sim {
+	return &Sim{
+		Game:   g,
+		Layout: NewLayout(g.rows, g.cols),
+		tpl:    t,
+	}
+}
+
+// Tap 点没开的格子是翻开，点翻开的数字是 chord，点到棋盘外面什么也不做
+func (s *Sim) Tap(p image.Point) error {
+	row, col, ok := s.At(p, s.rows, s.cols)
+	if !ok {
+		return nil
+	}
+	if s.State(row, col).IsNum() {
+		s.Chord(row, col)
+		return nil
+	}
+	s.Open(row, col)
+	return nil
+}
+
+// LongPress 插旗子或者拔掉旗子
+func (s *Sim) LongPress(p image.Point) error {
+	if row, col, ok := s.At(p, s.rows, s.cols); ok {
+		s.Flag(row, col)
+	}
+	return nil
+}
+
+// Back 没有弹窗
+func (s *Sim) Back() error {
+	return nil
+}
+
+// Screencap 和 adb 截图一样，调用的人负责 Close
+func (s *Sim) Screencap() (gocv.Mat, error) {
+	return Render(s.Game, s.Layout, s.tpl), nil
+}
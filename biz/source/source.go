@@ -0,0 +1,285 @@
+// Package source 一帧一帧拿截图：adb、图片文件、目录、视频、标准输入
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// FrameSource 截图的来源，Next 返回的 Mat 调用的人负责 Close，没有下一帧了返回 io.EOF
+// 出错的时候返回的是空的 gocv.Mat{}，没有分配，不要 Close
+type FrameSource interface {
+	Next() (gocv.Mat, error)
+	Close() error
+}
+
+/*
+Open 按名字打开来源，serial 是 adb -s，只有 adb 用
+
+	adb     adb screencap -p
+	adbraw  adb screencap 不压成 png，直接传像素
+	-       标准输入，一张接一张的 png
+	目录     里面的 png 和 jpg 按文件名排序
+	视频     .mp4 .avi .mkv .mov .webm
+	别的     当成一张图片
+*/
+func Open(name, serial string) (FrameSource, error) {
+	switch name {
+	case "adb":
+		return &ADB{Serial: serial}, nil
+	case "adbraw":
+		return &ADB{Serial: serial, Raw: true}, nil
+	case "-":
+		return NewReader(os.Stdin), nil
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return NewDir(name)
+	}
+	if videoExt[strings.ToLower(filepath.Ext(name))] {
+		return NewVideo(name)
+	}
+	return NewFiles(name), nil
+}
+
+var (
+	imageExt = map[string]bool{".png": true, ".jpg": true, ".jpeg": true}
+	videoExt = map[string]bool{".mp4": true, ".avi": true, ".mkv": true, ".mov": true, ".webm": true}
+)
+
+// ADB 用 adb exec-out screencap 截手机的屏幕
+type ADB struct {
+	Serial string // adb -s，空的话用唯一连着的设备
+	Raw    bool   // 不压成 png，传的数据多一些，但是手机上不用压缩，电脑上也不用解码，快很多
+}
+
+func (a *ADB) Next() (gocv.Mat, error) {
+	args := []string{"exec-out", "screencap"}
+	if !a.Raw {
+		args = append(args, "-p")
+	}
+	if a.Serial != "" {
+		args = append([]string{"-s", a.Serial}, args...)
+	}
+	buf, err := exec.Command("adb", args...).Output()
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("adb %v: %w", args, err)
+	}
+	if a.Raw {
+		return decodeRaw(buf)
+	}
+	return decode(buf)
+}
+
+func (a *ADB) Close() error {
+	return nil
+}
+
+// 不加 -p 时 screencap 的像素格式，只认每个像素4字节的
+const (
+	rgba8888 = 1
+	rgbx8888 = 2
+)
+
+// decodeRaw 不加 -p 的 screencap 转成和 png 一样的 BGRA
+func decodeRaw(buf []byte) (gocv.Mat, error) {
+	w, h, pix, err := splitRaw(buf)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	rgba, err := gocv.NewMatFromBytes(h, w, gocv.MatTypeCV8UC4, pix)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	defer rgba.Close()
+	dst := gocv.NewMat()
+	// 换一下 R 和 B，RGBA 到 BGRA 和 BGRA 到 RGBA 是一样的
+	gocv.CvtColor(rgba, &dst, gocv.ColorBGRAToRGBA)
+	return dst, nil
+}
+
+// splitRaw 前面是宽、高、像素格式各4字节，新一点的安卓后面还有4字节的色彩空间，然后是像素
+func splitRaw(buf []byte) (w, h int, pix []byte, err error) {
+	if len(buf) < 12 {
+		return 0, 0, nil, fmt.Errorf("source: raw screencap too short: %v bytes", len(buf))
+	}
+	w = int(binary.LittleEndian.Uint32(buf))
+	h = int(binary.LittleEndian.Uint32(buf[4:]))
+	format := binary.LittleEndian.Uint32(buf[8:])
+	if format != rgba8888 && format != rgbx8888 {
+		return 0, 0, nil, fmt.Errorf("source: raw screencap format %v, want RGBA_8888", format)
+	}
+	size := w * h * 4
+	for _, header := range []int{12, 16} {
+		if len(buf) == header+size {
+			return w, h, buf[header:], nil
+		}
+	}
+	return 0, 0, nil, fmt.Errorf("source: raw screencap %vx%v has %v bytes", w, h, len(buf))
+}
+
+func decode(buf []byte) (gocv.Mat, error) {
+	m, err := gocv.IMDecode(buf, gocv.IMReadUnchanged)
+	if err != nil {
+		m.Close()
+		return gocv.Mat{}, err
+	}
+	if m.Empty() {
+		m.Close()
+		return gocv.Mat{}, fmt.Errorf("source: cannot decode %v bytes", len(buf))
+	}
+	return m, nil
+}
+
+// Files 按顺序读一些图片文件
+type Files struct {
+	names []string
+	next  int
+}
+
+func NewFiles(names ...string) *Files {
+	return &Files{names: names}
+}
+
+// NewDir 目录里的 png 和 jpg 按文件名排序
+func NewDir(dir string) (*Files, error) {
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range list {
+		if !e.IsDir() && imageExt[strings.ToLower(filepath.Ext(e.Name()))] {
+			names = append(names, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(names)
+	return NewFiles(names...), nil
+}
+
+func (f *Files) Next() (gocv.Mat, error) {
+	if f.next >= len(f.names) {
+		return gocv.Mat{}, io.EOF
+	}
+	name := f.names[f.next]
+	f.next++
+	m := gocv.IMRead(name, gocv.IMReadUnchanged)
+	if m.Empty() {
+		m.Close()
+		return gocv.Mat{}, fmt.Errorf("source: cannot read %v", name)
+	}
+	return m, nil
+}
+
+func (f *Files) Close() error {
+	return nil
+}
+
+// Video 一帧一帧读录屏
+type Video struct {
+	Step int // 每几帧取一帧，0 和 1 都是每帧都取
+	vc   *gocv.VideoCapture
+}
+
+func NewVideo(name string) (*Video, error) {
+	vc, err := gocv.VideoCaptureFile(name)
+	if err != nil {
+		vc.Close()
+		return nil, err
+	}
+	return &Video{vc: vc}, nil
+}
+
+func (v *Video) Next() (gocv.Mat, error) {
+	step := v.Step
+	if step < 1 {
+		step = 1
+	}
+	m := gocv.NewMat()
+	for i := 0; i < step; i++ {
+		if !v.vc.Read(&m) || m.Empty() {
+			m.Close()
+			return gocv.Mat{}, io.EOF
+		}
+	}
+	return m, nil
+}
+
+func (v *Video) Close() error {
+	return v.vc.Close()
+}
+
+// Reader 从一个流里一张接一张地读 png，比如
+//
+//	adb exec-out 'while true; do screencap -p; done' | taptap -play -source -
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+func (r *Reader) Next() (gocv.Mat, error) {
+	buf, err := readPNG(r.r)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	return decode(buf)
+}
+
+func (r *Reader) Close() error {
+	return nil
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// maxChunk 一个 png 块最多多大，防止读到乱的数据分配太多内存
+const maxChunk = 1 << 28
+
+// readPNG 读出一整张 png：文件头后面是一个一个块，每块是长度、类型、数据、CRC，读到 IEND 为止
+// 流正好在两张图之间结束返回 io.EOF
+func readPNG(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	head := make([]byte, len(pngHeader))
+	if _, err := io.ReadFull(r, head); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("source: truncated png: %w", err)
+		}
+		return nil, err
+	}
+	if !bytes.Equal(head, pngHeader) {
+		return nil, fmt.Errorf("source: not a png: % x", head)
+	}
+	buf.Write(head)
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, fmt.Errorf("source: truncated png: %w", err)
+		}
+		n := binary.BigEndian.Uint32(chunk)
+		if n > maxChunk {
+			return nil, fmt.Errorf("source: png chunk %q too large: %v", chunk[4:], n)
+		}
+		buf.Write(chunk)
+		if _, err := io.CopyN(&buf, r, int64(n)+4); err != nil {
+			return nil, fmt.Errorf("source: truncated png: %w", err)
+		}
+		if string(chunk[4:]) == "IEND" {
+			return buf.Bytes(), nil
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSplitRaw(t *testing.T) {
+	raw := func(format uint32, header, pix int) []byte {
+		buf := make([]byte, header+pix)
+		binary.LittleEndian.PutUint32(buf, 2)
+		binary.LittleEndian.PutUint32(buf[4:], 3)
+		binary.LittleEndian.PutUint32(buf[8:], format)
+		return buf
+	}
+	for _, header := range []int{12, 16} {
+		w, h, pix, err := splitRaw(raw(rgba8888, header, 24))
+		if err != nil || w != 2 || h != 3 || len(pix) != 24 {
+			t.Fatalf("header %v: got %v %v %v %v", header, w, h, len(pix), err)
+		}
+	}
+	for _, buf := range [][]byte{raw(4, 12, 24), raw(rgba8888, 12, 20), {1, 2}} {
+		if _, _, _, err := splitRaw(buf); err == nil {
+			t.Fatalf("%v bytes: want error", len(buf))
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	a, b := pngBytes(t, 4, 3), pngBytes(t, 5, 6)
+	r := bufio.NewReader(bytes.NewReader(append(append([]byte{}, a...), b...)))
+	for _, want := range [][]byte{a, b} {
+		got, err := readPNG(r)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("got %v bytes %v, want %v", len(got), err, len(want))
+		}
+	}
+	if _, err := readPNG(r); err != io.EOF {
+		t.Fatalf("want EOF, got %v", err)
+	}
+	r = bufio.NewReader(bytes.NewReader(a[:len(a)-3]))
+	if _, err := readPNG(r); err == nil || err == io.EOF {
+		t.Fatalf("truncated: got %v", err)
+	}
+
+	src := NewReader(bytes.NewReader(b))
+	m, err := src.Next()
+	if err != nil || m.Rows() != 6 || m.Cols() != 5 {
+		t.Fatalf("got %v %v", m.Size(), err)
+	}
+	m.Close()
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, w := range map[string]int{"2.png": 20, "1.png": 10, "note.txt": 0} {
+		buf := []byte("not an image")
+		if w > 0 {
+			buf = pngBytes(t, w, 8)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src, err := Open(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for _, w := range []int{10, 20} {
+		m, err := src.Next()
+		if err != nil || m.Cols() != w {
+			t.Fatalf("got %v %v, want width %v", m.Size(), err, w)
+		}
+		m.Close()
+	}
+	if _, err := src.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("want EOF, got %v", err)
+	}
+
+	if _, err := Open(filepath.Join(dir, "missing.png"), ""); err == nil {
+		t.Fatal("want error")
+	}
+	src, _ = Open("adbraw", "abc")
+	if a, ok := src.(*ADB); !ok || !a.Raw || a.Serial != "abc" {
+		t.Fatalf("got %#v", src)
+	}
+}
+
+func TestVideo(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "play.avi")
+	vw, err := gocv.VideoWriterFile(name, "MJPG", 10, 64, 48, true)
+	if err != nil || !vw.IsOpened() {
+		t.Skip("cannot write video:", err)
+	}
+	frame := gocv.NewMatWithSize(48, 64, gocv.MatTypeCV8UC3)
+	defer frame.Close()
+	for i := 0; i < 3; i++ {
+		vw.Write(frame)
+	}
+	vw.Close()
+
+	src, err := Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	n := 0
+	for {
+		m, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || m.Cols() != 64 {
+			t.Fatalf("frame %v: %v %v", n, m.Size(), err)
+		}
+		m.Close()
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("got %v frames", n)
+	}
+}
@@ -0,0 +1,299 @@
+package stitch
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+var (
+	ErrNoOverlap = errors.New("stitch: 找不到和已有棋盘重合的位置")
+	ErrAmbiguous = errors.New("stitch: 有多个一样好的重合位置")
+	ErrPitch     = errors.New("stitch: 格子大小对不上，可能缩放了")
+	ErrGrid      = errors.New("stitch: 格子内容对得上的位置，网格线都对不上")
+)
+
+// Options 拼接参数
+type Options struct {
+	MinOverlap int     // 两帧之间至少要有几个已开的格子对上，默认3
+	MaxShift   int     // 相对上一帧最多平移多少格，0表示不限
+	PitchDiff  float64 // 格子像素大小允许的误差比例，默认0.1，也是网格线位置允许的误差
+}
+
+// Conflict 两帧里都开了的同一个格子识别得不一样
+type Conflict struct {
+	Pt    cell.Point // 坐标，和 Board.View 一致
+	Frame int        // 后加入的那一帧
+	Old   cell.State // 棋盘上原来的内容
+	New   cell.State // 这一帧识别的内容
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("(%v,%v) frame %v: %c != %c", c.Pt.X, c.Pt.Y, c.Frame, c.Old.Byte(), c.New.Byte())
+}
+
+// Board 把多张平移后的截图拼成一个大棋盘
+// 第一帧的左上角是原点，后面每一帧都先对齐网格，再用格子内容找平移量
+type Board struct {
+	opt       Options
+	cells     map[cell.Point]*cell.Cell
+	frames    []*view.View
+	offsets   []cell.Point // 每一帧左上角在原始坐标里的位置
+	conflicts []Conflict
+	pitch     float64
+	minRow    int
+	minCol    int
+	maxRow    int
+	maxCol    int
+}
+
+// New 空棋盘
+func New(opt Options) *Board {
+	if opt.MinOverlap <= 0 {
+		opt.MinOverlap = 3
+	}
+	if opt.PitchDiff <= 0 {
+		opt.PitchDiff = 0.1
+	}
+	return &Board{
+		opt:   opt,
+		cells: make(map[cell.Point]*cell.Cell),
+	}
+}
+
+// Stitch 按顺序拼接所有帧
+func Stitch(frames []*view.View, opt Options) (*Board, error) {
+	b := New(opt)
+	for i, v := range frames {
+		if _, err := b.Add(v); err != nil {
+			return b, fmt.Errorf("frame %v: %w", i, err)
+		}
+	}
+	return b, nil
+}
+
+// Add 把一帧加到棋盘上，返回这一帧左上角的原始坐标
+// 对齐后面的帧还要用它的格子位置，Board 用完之前不要关掉 v
+func (b *Board) Add(v *view.View) (offset cell.Point, err error) {
+	p := pitch(v)
+	if b.pitch > 0 && p > 0 && math.Abs(p-b.pitch) > b.pitch*b.opt.PitchDiff {
+		return offset, ErrPitch
+	}
+	if len(b.offsets) > 0 {
+		offset, err = b.register(v)
+		if err != nil {
+			return
+		}
+	}
+	if b.pitch == 0 {
+		b.pitch = p
+	}
+	b.merge(v, offset)
+	return
+}
+
+// register 在所有可能的平移里找对得最好的那个
+func (b *Board) register(v *view.View) (offset cell.Point, err error) {
+	last := b.offsets[len(b.offsets)-1]
+	bestScore, bestDist := math.MinInt, math.MaxInt
+	ambiguous := false
+	found := false
+	misaligned := false
+	for dr := b.minRow - v.Rows() + 1; dr <= b.maxRow; dr++ {
+		for dc := b.minCol - v.Cols() + 1; dc <= b.maxCol; dc++ {
+			dist := abs(dr-last.X) + abs(dc-last.Y)
+			if b.opt.MaxShift > 0 && (abs(dr-last.X) > b.opt.MaxShift || abs(dc-last.Y) > b.opt.MaxShift) {
+				continue
+			}
+			match, miss := b.score(v, dr, dc)
+			if match < b.opt.MinOverlap {
+				continue
+			}
+			if !b.aligned(v, dr, dc) {
+				misaligned = true
+				continue
+			}
+			// 一个对不上的格子抵掉两个对上的
+			score := match - 2*miss
+			switch {
+			case score > bestScore || (score == bestScore && dist < bestDist):
+				bestScore, bestDist = score, dist
+				offset = cell.Pt(dr, dc)
+				ambiguous = false
+				found = true
+			case score == bestScore && dist == bestDist:
+				ambiguous = true
+			}
+		}
+	}
+	if !found && misaligned {
+		return offset, ErrGrid
+	}
+	if !found {
+		return offset, ErrNoOverlap
+	}
+	if ambiguous {
+		return offset, ErrAmbiguous
+	}
+	return offset, nil
+}
+
+// score 平移 dr,dc 之后，已开的格子有几个对上、几个对不上
+// 没开的格子到处都是，不参与打分
+func (b *Board) score(v *view.View, dr, dc int) (match, miss int) {
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			old, ok := b.cells[cell.Pt(i+dr, j+dc)]
+			if !ok {
+				continue
+			}
+			c := v.GetCell(i, j)
+			if old.IsUnknown() || c.IsUnknown() {
+				continue
+			}
+			if old.State() == c.State() {
+				match++
+			} else {
+				miss++
+			}
+		}
+	}
+	return
+}
+
+// aligned 平移 dr,dc 之后，和之前每一帧重合的格子，中心点在两张截图上的位移都应该一样
+// 差得多说明两帧的网格线对不上，比如有一帧漏了一条线，格子内容碰巧对上了也不要
+func (b *Board) aligned(v *view.View, dr, dc int) bool {
+	tol := b.pitch * b.opt.PitchDiff
+	for k, f := range b.frames {
+		o := b.offsets[k]
+		var first image.Point
+		seen := false
+		for i := 0; i < v.Rows(); i++ {
+			for j := 0; j < v.Cols(); j++ {
+				fi, fj := i+dr-o.X, j+dc-o.Y
+				if fi < 0 || fi >= f.Rows() || fj < 0 || fj >= f.Cols() {
+					continue
+				}
+				d := v.GetCell(i, j).Point().Sub(f.GetCell(fi, fj).Point())
+				if !seen {
+					first, seen = d, true
+					continue
+				}
+				if math.Abs(float64(d.X-first.X)) > tol || math.Abs(float64(d.Y-first.Y)) > tol {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (b *Board) merge(v *view.View, offset cell.Point) {
+	frame := len(b.offsets)
+	if frame == 0 {
+		b.minRow, b.minCol = offset.X, offset.Y
+		b.maxRow, b.maxCol = offset.X+v.Rows()-1, offset.Y+v.Cols()-1
+	}
+	b.frames = append(b.frames, v)
+	b.offsets = append(b.offsets, offset)
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			p := cell.Pt(i+offset.X, j+offset.Y)
+			c := v.GetCell(i, j)
+			old, ok := b.cells[p]
+			// 一帧没开一帧开了是正常的，都开了还不一样才是识别错了
+			if ok && !old.IsUnknown() && !c.IsUnknown() && old.State() != c.State() {
+				b.conflicts = append(b.conflicts, Conflict{
+					Pt:    p,
+					Frame: frame,
+					Old:   old.State(),
+					New:   c.State(),
+				})
+			}
+			// 已开的格子比没开的可信
+			if !ok || (old.IsUnknown() && !c.IsUnknown()) {
+				b.cells[p] = c.Move(p.X, p.Y)
+			}
+		}
+	}
+	b.minRow = min(b.minRow, offset.X)
+	b.minCol = min(b.minCol, offset.Y)
+	b.maxRow = max(b.maxRow, offset.X+v.Rows()-1)
+	b.maxCol = max(b.maxCol, offset.Y+v.Cols()-1)
+}
+
+// View 拼好的整个棋盘，左上角是 0,0
+// 没有被任何一帧拍到的格子当作没开的格子
+// 格子的小图和原来的帧共用，关掉原来的帧就行
+func (b *Board) View() *view.View {
+	rows := b.maxRow - b.minRow + 1
+	cols := b.maxCol - b.minCol + 1
+	list := make([]*cell.Cell, 0, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			c, ok := b.cells[cell.Pt(i+b.minRow, j+b.minCol)]
+			if !ok {
+				list = append(list, cell.New(i, j, 0, 0, nil, cell.Unknown))
+				continue
+			}
+			list = append(list, c.Move(i, j))
+		}
+	}
+	return view.NewView(list, cols)
+}
+
+// Offset 第 i 帧的左上角在 View 里的坐标
+func (b *Board) Offset(i int) cell.Point {
+	o := b.offsets[i]
+	return cell.Pt(o.X-b.minRow, o.Y-b.minCol)
+}
+
+// Frames 一共拼了几帧
+func (b *Board) Frames() int {
+	return len(b.offsets)
+}
+
+// Conflicts 拼接时发现的不一致
+func (b *Board) Conflicts() (list []Conflict) {
+	for _, c := range b.conflicts {
+		c.Pt = cell.Pt(c.Pt.X-b.minRow, c.Pt.Y-b.minCol)
+		list = append(list, c)
+	}
+	return
+}
+
+// pitch 相邻两列中心点的平均像素距离
+func pitch(v *view.View) float64 {
+	if v.Rows() == 0 || v.Cols() < 2 {
+		return 0
+	}
+	first := v.GetCell(0, 0).Point()
+	last := v.GetCell(0, v.Cols()-1).Point()
+	return float64(last.X-first.X) / float64(v.Cols()-1)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package stitch
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+)
+
+func newView(rows []string) *view.View {
+	return newViewAt(rows, func(i, j int) image.Point { return image.Pt(j*50, i*50) })
+}
+
+// newViewAt at 给出每个格子中心在截图上的位置
+func newViewAt(rows []string, at func(i, j int) image.Point) *view.View {
+	var list []*cell.Cell
+	for i, row := range rows {
+		for j := 0; j < len(row); j++ {
+			s, _ := cell.ParseState(row[j])
+			p := at(i, j)
+			list = append(list, cell.New(i, j, p.X, p.Y, nil, s))
+		}
+	}
+	return view.NewView(list, len(rows[0]))
+}
+
+func TestStitch(t *testing.T) {
+	a := newView([]string{
+		"1_2",
+		"12_",
+		"_31",
+	})
+	// 往右下平移了一格，1,2 开了，2,2 识别错了
+	b := newView([]string{
+		"21_",
+		"342",
+		"_10",
+	})
+	board, err := Stitch([]*view.View{a, b}, Options{MinOverlap: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := board.Offset(1); o != cell.Pt(1, 1) {
+		t.Fatalf("offset %v", o)
+	}
+	v := board.View()
+	if v.Rows() != 4 || v.Cols() != 4 {
+		t.Fatalf("size %v*%v", v.Rows(), v.Cols())
+	}
+	if c := v.GetCell(1, 2); c.Byte() != '1' || c.Pt() != cell.Pt(1, 2) {
+		t.Fatalf("1,2 is %v", c)
+	}
+	if c := v.GetCell(3, 0); !c.IsUnknown() {
+		t.Fatalf("3,0 is %v", c)
+	}
+	if c := v.GetCell(3, 3); c.Byte() != '0' {
+		t.Fatalf("3,3 is %v", c)
+	}
+	// 1,2 只是后来开了，不算
+	conflicts := board.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Pt != cell.Pt(2, 2) {
+		t.Fatalf("conflicts %v", conflicts)
+	}
+}
+
+func TestStitchNoOverlap(t *testing.T) {
+	a := newView([]string{"12", "34"})
+	b := newView([]string{"56", "78"})
+	if _, err := Stitch([]*view.View{a, b}, Options{}); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+// 平移不是整格的像素也没关系，只要网格线在重合的地方对得上
+func TestStitchGrid(t *testing.T) {
+	a := newView([]string{
+		"1_2",
+		"12_",
+		"_31",
+	})
+	rows := []string{
+		"21_",
+		"341",
+		"_10",
+	}
+	b := newViewAt(rows, func(i, j int) image.Point { return image.Pt(17+j*50, 9+i*50) })
+	board, err := Stitch([]*view.View{a, b}, Options{MinOverlap: 2})
+	if err != nil || board.Offset(1) != cell.Pt(1, 1) {
+		t.Fatalf("got %v %v", board.Offset(1), err)
+	}
+
+	// 这一帧第1列和第2列之间漏了一条线，第1列往右偏了半格
+	b = newViewAt(rows, func(i, j int) image.Point {
+		if j == 1 {
+			return image.Pt(j*50+25, i*50)
+		}
+		return image.Pt(j*50, i*50)
+	})
+	if _, err := Stitch([]*view.View{a, b}, Options{MinOverlap: 2}); !errors.Is(err, ErrGrid) {
+		t.Fatalf("want ErrGrid, got %v", err)
+	}
+}
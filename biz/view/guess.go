@@ -0,0 +1,71 @@
+package view
+
+import "taptap/biz/cell"
+
+// density 不知道总雷数的时候，不挨着数字的格子按这个概率算，差不多是高级难度
+const density = 0.2
+
+// Guess 推不出来的时候挑一个最不像雷的没开的格子，返回这个格子和它是雷的概率
+// 挨着数字的格子和 RuleEnum 一样穷举，按每组放法的多少算概率，
+// 别的格子把剩下的雷平均分，几组之间的放法没有加权，所以只是个近似
+// 没有没开的格子返回 nil，不会改棋盘
+func Guess(v *View, opt Options) (*cell.Cell, float64) {
+	s := newSolver(v, opt)
+	prob := make(map[int]float64)
+	expect := 0.0
+	if vars, cons, err := s.frontier(); err == nil {
+		left := s.left()
+		for _, group := range groups(len(vars), cons) {
+			if len(group.vars) > s.opt.MaxEnum {
+				continue
+			}
+			mines, total := group.solve(left)
+			if total == 0 {
+				continue
+			}
+			for i, k := range group.vars {
+				p := float64(mines[i]) / float64(total)
+				prob[vars[k].Index(v.cols)] = p
+				expect += p
+			}
+		}
+	}
+
+	others := 0
+	for _, c := range v.list {
+		if c.IsUnknown() {
+			if _, ok := prob[c.Index(v.cols)]; !ok {
+				others++
+			}
+		}
+	}
+	rest := density
+	if left := s.left(); left >= 0 && others > 0 {
+		rest = (float64(left) - expect) / float64(others)
+		if rest < 0 {
+			rest = 0
+		}
+		if rest > 1 {
+			rest = 1
+		}
+	}
+
+	var best *cell.Cell
+	bestProb := 2.0
+	for _, c := range v.list {
+		if !c.IsUnknown() {
+			continue
+		}
+		p, ok := prob[c.Index(v.cols)]
+		if !ok {
+			p = rest
+		}
+		if p < bestProb {
+			best, bestProb = c, p
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	return best, bestProb
+}
@@ -0,0 +1,256 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"taptap/biz/cell"
+)
+
+/*
+模式用文本写，一行是棋盘的一行，空格会被忽略，转90度和镜像的会自动生成
+
+	0-8  翻开的数字，减掉周围已经知道的雷以后是这个数
+	_    没开的格子
+	m    没开的格子，匹配上以后是雷
+	s    没开的格子，匹配上以后不是雷
+	X    不是没开的格子：翻开了、已经知道是雷、或者在棋盘外面
+	.    什么都行
+
+数字周围的8个格子都要写在模式里，所以数字不能在最外面一圈，
+这样数字周围没开的格子就都在模式里了，推出来的东西才靠得住
+*/
+
+// Pattern 一个有名字的模式
+type Pattern struct {
+	Name     string
+	Text     string
+	variants [][]string // 转过和镜像过的，去掉了重复的
+}
+
+// Patterns 所有的模式，都是玩家常用的
+var Patterns = []*Pattern{
+	NewPattern("1-2-1", `
+		s m s m s
+		X 1 2 1 X
+		X X X X X
+	`),
+	NewPattern("1-2-2-1", `
+		s s m m s s
+		X 1 2 2 1 X
+		X X X X X X
+	`),
+	NewPattern("1-1 wall", `
+		X _ _ s
+		X 1 1 X
+		X X X X
+	`),
+	NewPattern("1-2", `
+		s _ _ m
+		X 1 2 X
+		X X X X
+	`),
+	NewPattern("1-2 wall", `
+		X _ _ m
+		X 1 2 X
+		X X X X
+	`),
+	// 下面的 1 两边有一个雷，上面中间的 1 就够了，它上面3个都不是雷
+	NewPattern("1-1-1 T", `
+		_ s s s _
+		X 1 1 1 X
+		X _ 1 _ X
+		X X X X X
+	`),
+	// 拐角上的 1 和两边的 1 一起，挨着拐角的两个都不是雷
+	NewPattern("1-1 corner", `
+		_ s _ _ X
+		s 1 1 1 X
+		_ 1 X X X
+		_ 1 X X X
+		X X X X X
+	`),
+}
+
+// NewPattern 解析模式，写错了直接 panic，模式都是写死在代码里的
+func NewPattern(name, text string) *Pattern {
+	var grid []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+		if line == "" {
+			continue
+		}
+		if len(grid) > 0 && len(line) != len(grid[0]) {
+			panic(fmt.Sprintf("pattern %v: rows have different length", name))
+		}
+		for i := 0; i < len(line); i++ {
+			if !strings.ContainsRune("012345678_msX.", rune(line[i])) {
+				panic(fmt.Sprintf("pattern %v: bad char %q", name, line[i]))
+			}
+		}
+		grid = append(grid, line)
+	}
+	if len(grid) == 0 {
+		panic(fmt.Sprintf("pattern %v: empty", name))
+	}
+	for r, line := range grid {
+		for c := 0; c < len(line); c++ {
+			if !isDigit(line[c]) {
+				continue
+			}
+			if r == 0 || c == 0 || r == len(grid)-1 || c == len(line)-1 {
+				panic(fmt.Sprintf("pattern %v: number at the edge", name))
+			}
+			for _, p := range cell.Pt(r, c).GetSub() {
+				if grid[p.X][p.Y] == '.' {
+					panic(fmt.Sprintf("pattern %v: '.' next to a number", name))
+				}
+			}
+		}
+	}
+
+	p := &Pattern{Name: name, Text: strings.Join(grid, "\n")}
+	seen := make(map[string]bool)
+	for _, mirror := range []bool{false, true} {
+		g := grid
+		if mirror {
+			g = mirrorGrid(g)
+		}
+		for i := 0; i < 4; i++ {
+			if key := strings.Join(g, "\n"); !seen[key] {
+				seen[key] = true
+				p.variants = append(p.variants, g)
+			}
+			g = rotateGrid(g)
+		}
+	}
+	return p
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '8'
+}
+
+// rotateGrid 顺时针转90度
+func rotateGrid(g []string) []string {
+	h, w := len(g), len(g[0])
+	ret := make([]string, w)
+	for r := 0; r < w; r++ {
+		b := make([]byte, h)
+		for c := 0; c < h; c++ {
+			b[c] = g[h-1-c][r]
+		}
+		ret[r] = string(b)
+	}
+	return ret
+}
+
+// mirrorGrid 左右翻过来
+func mirrorGrid(g []string) []string {
+	ret := make([]string, len(g))
+	for r, line := range g {
+		b := []byte(line)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		ret[r] = string(b)
+	}
+	return ret
+}
+
+// Match 一个模式在棋盘上匹配上了
+type Match struct {
+	Name  string
+	At    cell.Point // 模式（转过以后）左上角在棋盘上的位置，可以在棋盘外面
+	Mines []*cell.Cell
+	Safe  []*cell.Cell
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%v at %v,%v mines %v safe %v", m.Name, m.At.X, m.At.Y, m.Mines, m.Safe)
+}
+
+// FindPattern 用所有的 Patterns 在整个棋盘上找，返回匹配上而且能推出东西的
+// 模式都是按普通棋盘写的，别的 Topology 下不找
+func (v *View) FindPattern() (list []Match) {
+	if _, ok := v.Topology().(Square); !ok {
+		return nil
+	}
+	for _, p := range Patterns {
+		list = append(list, v.MatchPattern(p)...)
+	}
+	return
+}
+
+// MatchPattern 用一个模式的所有方向在整个棋盘上找
+func (v *View) MatchPattern(p *Pattern) (list []Match) {
+	for _, g := range p.variants {
+		h, w := len(g), len(g[0])
+		for x := -h + 1; x < v.Rows(); x++ {
+			for y := -w + 1; y < v.Cols(); y++ {
+				if m, ok := v.match(g, x, y); ok {
+					m.Name = p.Name
+					list = append(list, m)
+				}
+			}
+		}
+	}
+	return
+}
+
+// match 模式左上角放在 x,y 上看对不对得上
+func (v *View) match(g []string, x, y int) (m Match, ok bool) {
+	for r, line := range g {
+		for c := 0; c < len(line); c++ {
+			ch := line[c]
+			if ch == '.' {
+				continue
+			}
+			row, col := x+r, y+c
+			in := 0 <= row && row < v.Rows() && 0 <= col && col < v.Cols()
+			if !in {
+				if ch == 'X' {
+					continue
+				}
+				return m, false
+			}
+			one := v.GetCell(row, col)
+			switch {
+			case ch == 'X':
+				if one.IsUnknown() {
+					return m, false
+				}
+			case isDigit(ch):
+				if n, err := v.effective(one); err != nil || n != int(ch-'0') {
+					return m, false
+				}
+			default:
+				if !one.IsUnknown() {
+					return m, false
+				}
+				if ch == 'm' {
+					m.Mines = append(m.Mines, one)
+				}
+				if ch == 's' {
+					m.Safe = append(m.Safe, one)
+				}
+			}
+		}
+	}
+	m.At = cell.Pt(x, y)
+	return m, len(m.Mines)+len(m.Safe) > 0
+}
+
+// effective 数字减掉周围已经知道的雷，不是数字报错
+func (v *View) effective(c *cell.Cell) (int, error) {
+	n, err := c.Num()
+	if err != nil {
+		return 0, err
+	}
+	for _, one := range v.Around(c) {
+		if one.IsMineKnown() {
+			n--
+		}
+	}
+	return n, nil
+}
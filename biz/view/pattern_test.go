@@ -0,0 +1,246 @@
+package view
+
+import (
+	"testing"
+
+	"taptap/biz/cell"
+)
+
+// TestPatternSound 每个模式穷举所有放雷的方法，m 一定是雷，s 一定不是雷
+func TestPatternSound(t *testing.T) {
+	for _, p := range Patterns {
+		g := p.variants[0]
+		var vars []cell.Point
+		for r, line := range g {
+			for c := 0; c < len(line); c++ {
+				if ch := line[c]; ch == '_' || ch == 'm' || ch == 's' {
+					vars = append(vars, cell.Pt(r, c))
+				}
+			}
+		}
+		solutions := 0
+		mines := make([]int, len(vars))
+		for bits := 0; bits < 1<<len(vars); bits++ {
+			mine := make(map[cell.Point]bool)
+			for i, pt := range vars {
+				mine[pt] = bits&(1<<i) != 0
+			}
+			ok := true
+			for r, line := range g {
+				for c := 0; c < len(line); c++ {
+					if !isDigit(line[c]) {
+						continue
+					}
+					n := 0
+					for _, q := range cell.Pt(r, c).GetSub() {
+						if mine[cell.Pt(q.X, q.Y)] {
+							n++
+						}
+					}
+					ok = ok && n == int(line[c]-'0')
+				}
+			}
+			if !ok {
+				continue
+			}
+			solutions++
+			for i, pt := range vars {
+				if mine[pt] {
+					mines[i]++
+				}
+			}
+		}
+		if solutions == 0 {
+			t.Fatalf("%v: no solution", p.Name)
+		}
+		for i, pt := range vars {
+			switch g[pt.X][pt.Y] {
+			case 'm':
+				if mines[i] != solutions {
+					t.Fatalf("%v: %v is not always a mine", p.Name, pt)
+				}
+			case 's':
+				if mines[i] != 0 {
+					t.Fatalf("%v: %v is not always safe", p.Name, pt)
+				}
+			}
+		}
+	}
+}
+
+func TestPatternVariants(t *testing.T) {
+	for _, p := range Patterns {
+		// 1-2-1 左右对称，镜像以后一样，只有4个方向，拐角沿对角线对称，也一样
+		want := 8
+		switch p.Name {
+		case "1-2-1", "1-2-2-1", "1-1-1 T", "1-1 corner":
+			want = 4
+		}
+		if len(p.variants) != want {
+			t.Fatalf("%v: got %v variants, want %v", p.Name, len(p.variants), want)
+		}
+	}
+}
+
+func patternByName(name string) *Pattern {
+	for _, p := range Patterns {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// pts 一对一对的坐标
+func pts(xy ...int) (list []cell.Point) {
+	for i := 0; i+1 < len(xy); i += 2 {
+		list = append(list, cell.Pt(xy[i], xy[i+1]))
+	}
+	return
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		name  string
+		board string
+		mines []cell.Point
+		safe  []cell.Point
+	}{
+		{"1-2-1", `
+			_____
+			11211
+			00000
+		`, pts(0, 1, 0, 3), pts(0, 0, 0, 2, 0, 4)},
+		// 竖过来
+		{"1-2-1", `
+			_10
+			_10
+			_20
+			_10
+			_10
+		`, pts(1, 0, 3, 0), pts(0, 0, 2, 0, 4, 0)},
+		// 旗子算进去以后才是 1-2-1
+		{"1-2-1", `
+			_____
+			12321
+			00f00
+		`, pts(0, 1, 0, 3), pts(0, 0, 0, 2, 0, 4)},
+		{"1-2-2-1", `
+			______
+			112211
+			000000
+		`, pts(0, 2, 0, 3), pts(0, 0, 0, 1, 0, 4, 0, 5)},
+		// 左边是墙
+		{"1-1 wall", `
+			___
+			110
+			000
+		`, nil, pts(0, 2)},
+		// 右边是墙，下边是墙
+		{"1-1 wall", `
+			000
+			011
+			___
+		`, nil, pts(2, 0)},
+		{"1-2", `
+			____
+			0120
+			0000
+		`, pts(0, 3), pts(0, 0)},
+		{"1-2 wall", `
+			___
+			120
+			000
+		`, pts(0, 2), nil},
+		{"1-1-1 T", `
+			_____
+			11111
+			1_1_0
+			11100
+		`, nil, pts(0, 1, 0, 2, 0, 3)},
+		// 倒过来
+		{"1-1-1 T", `
+			00111
+			0_1_1
+			11111
+			_____
+		`, nil, pts(3, 1, 3, 2, 3, 3)},
+		{"1-1 corner", `
+			____0
+			_1110
+			_1000
+			_1000
+			11000
+		`, nil, pts(0, 1, 1, 0)},
+		// 右下角
+		{"1-1 corner", `
+			00011
+			0001_
+			0001_
+			0111_
+			0____
+		`, nil, pts(3, 4, 4, 3)},
+	}
+	for _, c := range cases {
+		v, err := Parse(c.board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := v.MatchPattern(patternByName(c.name))
+		if len(list) != 1 {
+			t.Fatalf("%v: got %v\n%v", c.name, list, v)
+		}
+		m := list[0]
+		if m.Name != c.name || len(m.Mines) != len(c.mines) || len(m.Safe) != len(c.safe) {
+			t.Fatalf("%v: got %v\n%v", c.name, m, v)
+		}
+		for _, pt := range c.mines {
+			if !hasCell(m.Mines, pt.X, pt.Y) {
+				t.Fatalf("%v: got %v\n%v", c.name, m, v)
+			}
+		}
+		for _, pt := range c.safe {
+			if !hasCell(m.Safe, pt.X, pt.Y) {
+				t.Fatalf("%v: got %v\n%v", c.name, m, v)
+			}
+		}
+	}
+}
+
+func TestMatchPatternMiss(t *testing.T) {
+	// 2 下面还有没开的格子，不是 1-2-1
+	v, err := Parse(`
+		_____
+		11211
+		00_00
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := v.MatchPattern(patternByName("1-2-1")); len(list) != 0 {
+		t.Fatalf("got %v", list)
+	}
+}
+
+func TestSolvePattern(t *testing.T) {
+	v, err := Parse(`
+		_____
+		11211
+		00000
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := Solve(v, Options{Rules: RulePattern, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Mines) != 2 || len(ret.Safe) != 3 {
+		t.Fatalf("got %v %v", ret.Mines, ret.Safe)
+	}
+	for _, d := range append(ret.Mines, ret.Safe...) {
+		if d.Rule != RulePattern || d.Pattern != "1-2-1" {
+			t.Fatalf("got %v", d)
+		}
+	}
+}
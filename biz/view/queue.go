@@ -0,0 +1,137 @@
+package view
+
+import (
+	"fmt"
+
+	"taptap/biz/cell"
+)
+
+/*
+runQueue 和 run 推出来的东西一样，但是不每轮都扫整个棋盘
+
+一开始所有的数字都在队列里，拿出来一个数字，只用它和它附近的数字推（RuleBoom RuleNum RuleDiff），
+推出来的格子变了以后，只有这个格子周围的数字会受影响，把它们放回队列。
+队列空了再用一次要看整个棋盘的规则（RuleWa RulePattern RuleCount RuleEnum），
+推出新东西就接着跑队列，推不出来就停下。
+*/
+func (s *solver) runQueue() error {
+	v := s.view
+	if err := v.Check(); err != nil {
+		return err
+	}
+
+	var queue []*cell.Cell
+	in := make([]bool, len(v.list))
+	push := func(c *cell.Cell) {
+		if index := c.Index(v.cols); c.IsNum() && !in[index] {
+			in[index] = true
+			queue = append(queue, c)
+		}
+	}
+	for _, c := range v.list {
+		push(c)
+	}
+
+	for depth := 1; s.opt.MaxDepth == 0 || depth <= s.opt.MaxDepth; depth++ {
+		s.depth = depth
+		for len(queue) > 0 {
+			c := queue[0]
+			queue = queue[1:]
+			in[c.Index(v.cols)] = false
+			if err := s.local(c); err != nil {
+				return err
+			}
+			changed, err := s.accept()
+			if err != nil {
+				return err
+			}
+			for _, one := range changed {
+				for _, n := range v.Around(one) {
+					push(n)
+				}
+			}
+		}
+
+		if err := s.global(); err != nil {
+			return err
+		}
+		changed, err := s.accept()
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			break
+		}
+		for _, one := range changed {
+			for _, n := range v.Around(one) {
+				push(n)
+			}
+		}
+	}
+	return nil
+}
+
+// local 只看数字 c 和 GetRel 里的数字
+func (s *solver) local(c *cell.Cell) error {
+	v := s.view
+	n, list, err := s.around(c)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > len(list) {
+		return fmt.Errorf("%w: %v 周围的雷不对", ErrContradiction, c)
+	}
+	if n == 0 && s.opt.Rules&RuleNum != 0 {
+		s.add(RuleNum, false, list)
+	}
+	if n > 0 && n == len(list) && s.opt.Rules&RuleBoom != 0 {
+		s.add(RuleBoom, true, list)
+	}
+	if len(list) == 0 || s.opt.Rules&RuleDiff == 0 {
+		return nil
+	}
+
+	// 和 FindDiff 一样：只在 c 周围的里面最多有 n-m 个雷，只在 d 周围的里面最少有 0 个，
+	// 只在 c 周围的格子数正好是 n-m 时，这些全是雷，只在 d 周围的全不是雷
+	for _, d := range v.GetRel(c) {
+		m, other, err := s.around(d)
+		if err != nil {
+			continue
+		}
+		onlyC, onlyD := without(list, other), without(other, list)
+		if n-m == len(onlyC) {
+			s.add(RuleDiff, true, onlyC)
+			s.add(RuleDiff, false, onlyD)
+		}
+		if m-n == len(onlyD) {
+			s.add(RuleDiff, true, onlyD)
+			s.add(RuleDiff, false, onlyC)
+		}
+	}
+	return nil
+}
+
+// around 数字还差几个雷，以及周围没开、也还没推出来的格子，c 不是数字报错
+func (s *solver) around(c *cell.Cell) (n int, list []*cell.Cell, err error) {
+	if n, err = c.Num(); err != nil {
+		return
+	}
+	for _, one := range s.view.Around(c) {
+		if one.IsMineKnown() {
+			n--
+		} else if s.unknown(one) {
+			list = append(list, one)
+		}
+	}
+	return
+}
+
+// without 在 a 里不在 b 里的格子
+func without(a, b []*cell.Cell) (ret []*cell.Cell) {
+	for _, c := range a {
+		if !contains(b, c) {
+			ret = append(ret, c)
+		}
+	}
+	return
+}
@@ -0,0 +1,153 @@
+package view
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomBoard 随机放雷，从没雷的0开始一片一片翻开，直到翻开一半不是雷的格子
+func randomBoard(rows, cols, mines int, seed int64) (v *View, mine []bool) {
+	rnd := rand.New(rand.NewSource(seed))
+	mine = make([]bool, rows*cols)
+	for _, i := range rnd.Perm(rows * cols)[:mines] {
+		mine[i] = true
+	}
+	num := make([]int, rows*cols)
+	around := func(i int, f func(j int)) {
+		x, y := i/cols, i%cols
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if (dx != 0 || dy != 0) && 0 <= x+dx && x+dx < rows && 0 <= y+dy && y+dy < cols {
+					f((x+dx)*cols + y + dy)
+				}
+			}
+		}
+	}
+	for i := range num {
+		around(i, func(j int) {
+			if mine[j] {
+				num[i]++
+			}
+		})
+	}
+
+	open := make([]bool, rows*cols)
+	opened := 0
+	var flood func(i int)
+	flood = func(i int) {
+		if open[i] || mine[i] {
+			return
+		}
+		open[i] = true
+		opened++
+		if num[i] == 0 {
+			around(i, flood)
+		}
+	}
+	for _, i := range rnd.Perm(rows * cols) {
+		if opened*2 >= rows*cols-mines {
+			break
+		}
+		if !mine[i] && num[i] == 0 {
+			flood(i)
+		}
+	}
+
+	var b strings.Builder
+	for i := range num {
+		if open[i] {
+			b.WriteByte(byte('0' + num[i]))
+		} else {
+			b.WriteByte('_')
+		}
+		if (i+1)%cols == 0 {
+			b.WriteByte('\n')
+		}
+	}
+	v, err := Parse(b.String())
+	if err != nil {
+		panic(err)
+	}
+	return v, mine
+}
+
+// deductions 推出来的都要对，返回推出来的格子
+func deductions(t *testing.T, v *View, ret *Result, mine []bool) map[int]bool {
+	t.Helper()
+	got := make(map[int]bool)
+	for _, list := range [][]Deduction{ret.Mines, ret.Safe} {
+		for _, d := range list {
+			index := d.Cell.Index(v.Cols())
+			if d.Mine != mine[index] {
+				t.Fatalf("wrong %v", d)
+			}
+			got[index] = d.Mine
+		}
+	}
+	return got
+}
+
+func TestSolveQueue(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		v, mine := randomBoard(16, 30, 99, seed)
+		before := v.String()
+		scan, err := Solve(v, Options{Mines: 99})
+		if err != nil {
+			t.Fatalf("seed %v: %v\n%v", seed, err, v)
+		}
+		queue, err := Solve(v, Options{Mines: 99, Queue: true})
+		if err != nil {
+			t.Fatalf("seed %v: %v\n%v", seed, err, v)
+		}
+		if v.String() != before {
+			t.Fatalf("seed %v: board changed", seed)
+		}
+		// FindBoom FindDiff 把推出来不是雷、但还没开的格子也当成没开的，所以队列推出来的只会更多
+		a, b := deductions(t, v, scan, mine), deductions(t, v, queue, mine)
+		for index := range a {
+			if _, ok := b[index]; !ok {
+				t.Fatalf("seed %v: queue missed %v\n%v", seed, v.list[index], v)
+			}
+		}
+	}
+
+	// 只用局部的规则也能推到底，矛盾一样能发现
+	v, _ := Parse(`
+		01_
+		01_
+		___
+	`)
+	ret, err := Solve(v, Options{Rules: RuleBoom | RuleNum | RuleDiff, Queue: true})
+	if err != nil || len(ret.Mines)+len(ret.Safe) == 0 {
+		t.Fatalf("got %v %v", ret, err)
+	}
+	v, _ = Parse(`
+		4_
+		__
+	`)
+	if _, err := Solve(v, Options{Rules: RuleNum | RuleBoom, Queue: true}); err == nil {
+		t.Fatal("want contradiction")
+	}
+}
+
+// 大棋盘上每轮扫整个棋盘和用队列比
+func BenchmarkSolve(b *testing.B) {
+	for _, size := range []int{30, 60, 120} {
+		v, _ := randomBoard(size, size, size*size/6, 1)
+		for _, queue := range []bool{false, true} {
+			name := fmt.Sprintf("%vx%v/scan", size, size)
+			if queue {
+				name = fmt.Sprintf("%vx%v/queue", size, size)
+			}
+			b.Run(name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := Solve(v, Options{Rules: RuleBoom | RuleNum | RuleDiff, Queue: queue}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
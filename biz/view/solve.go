@@ -0,0 +1,578 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"taptap/biz/cell"
+
+	"golang.org/x/exp/slog"
+)
+
+var ErrContradiction = errors.New("view: contradiction")
+
+// Rule 推理用的规则，可以组合
+type Rule int
+
+const (
+	RuleBoom    Rule = 1 << iota // FindBoom 没开的格子和数字一样多
+	RuleNum                      // FindNum 旗子和数字一样多
+	RuleDiff                     // FindDiff 两个数字相减
+	RuleWa                       // FindWa 挖掉几个数字
+	RuleCount                    // 用总雷数，需要 Options.Mines
+	RuleEnum                     // 边界上没开的格子穷举所有可能
+	RulePattern                  // Patterns 里的模式
+
+	RuleAll = RuleBoom | RuleNum | RuleDiff | RuleWa | RuleCount | RuleEnum | RulePattern
+)
+
+var ruleNames = []string{"boom", "num", "diff", "wa", "count", "enum", "pattern"}
+
+func (r Rule) String() string {
+	var list []string
+	for i, name := range ruleNames {
+		if r&(1<<i) != 0 {
+			list = append(list, name)
+		}
+	}
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, "|")
+}
+
+// Options 求解的参数
+type Options struct {
+	Rules    Rule     // 用哪些规则，0 表示全部
+	MaxDepth int      // 最多推几轮，每一轮都用上一轮的结果，0 表示不限
+	Mines    int      // 总雷数，大于0才会用 RuleCount，RuleEnum 也会用它剪枝
+	MaxEnum  int      // RuleEnum 一组最多穷举几个格子，0 表示 20
+	InPlace  bool     // 在传进来的棋盘上标雷，可以用 Snapshot/Restore 撤销，不然在副本上推
+	Variant  *Variant // 变种扫雷，nil 是普通扫雷，变种只用 RuleEnum 穷举，这时不能给 Rules 和 Queue
+	Queue    bool     // 用工作队列，格子变了只重看它周围的数字，这时 MaxDepth 是最多用几次整个棋盘的规则
+
+	Logger *slog.Logger // 每推出来一个格子记一条 debug 日志，nil 不记
+}
+
+// Deduction 推出来的一个格子，以及是怎么推出来的
+type Deduction struct {
+	Cell    *cell.Cell // 传进来的棋盘上的格子
+	Mine    bool       // Count 大于0
+	Count   int        // 有几个雷，普通扫雷里是雷就是1，变种里可以是2或者负数，以这个为准
+	Rule    Rule       // 哪条规则推出来的
+	Pattern string     // RulePattern 推出来的话，是哪个模式
+	Depth   int        // 第几轮推出来的，从1开始
+}
+
+func (d Deduction) String() string {
+	what := "safe"
+	if d.Mine {
+		what = "mine"
+	}
+	if d.Pattern != "" {
+		return fmt.Sprintf("%v %v by %v %v #%v", d.Cell, what, d.Rule, d.Pattern, d.Depth)
+	}
+	return fmt.Sprintf("%v %v by %v #%v", d.Cell, what, d.Rule, d.Depth)
+}
+
+// Result 求解的结果，每个格子只出现一次
+type Result struct {
+	Mines    []Deduction
+	Safe     []Deduction
+	Negative []Deduction  // 变种里雷数是负数的格子，不是雷，也不能点
+	Skipped  []*cell.Cell // 最后一轮 RuleEnum 因为一组超过 Options.MaxEnum 没有穷举的格子，有的话推不下去不一定是要猜
+}
+
+// Cells 推出来的雷和一定不是雷的格子
+func (r *Result) Cells() (mines, safe []*cell.Cell) {
+	for _, d := range r.Mines {
+		mines = append(mines, d.Cell)
+	}
+	for _, d := range r.Safe {
+		safe = append(safe, d.Cell)
+	}
+	return
+}
+
+// Solve 按 Options 反复用规则推理，直到推不出新东西
+// 推出来的雷会标成 cell.Marked 再推下一轮，和屏幕上插的旗子分开，
+// Options.InPlace 为 false 时不会改传进来的棋盘
+// 发现矛盾时返回 ErrContradiction，Result 里是矛盾之前推出来的
+func Solve(v *View, opt Options) (*Result, error) {
+	if !opt.Variant.classic() && (opt.Queue || opt.Rules != 0 && opt.Rules != RuleEnum) {
+		return &Result{}, fmt.Errorf("view: variant only uses %v, got rules %v queue %v", RuleEnum, opt.Rules, opt.Queue)
+	}
+	s := newSolver(v, opt)
+	if !opt.InPlace {
+		s.view = v.Clone()
+	}
+	err := s.run()
+	return &s.result, err
+}
+
+func newSolver(v *View, opt Options) *solver {
+	if opt.Rules == 0 {
+		opt.Rules = RuleAll
+	}
+	if opt.MaxEnum <= 0 {
+		opt.MaxEnum = 20
+	}
+	return &solver{
+		src:   v,
+		view:  v,
+		opt:   opt,
+		safe:  make(map[int]bool),
+		done:  make(map[int]bool),
+		value: make(map[int]int),
+	}
+}
+
+type solver struct {
+	src    *View        // 传进来的棋盘
+	view   *View        // 在这个上面标雷
+	opt    Options      //
+	safe   map[int]bool // 推出来不是雷的格子，还没开所以只能记在这里
+	done   map[int]bool // 已经推出来的格子
+	value  map[int]int  // 推出来的格子有几个雷
+	found  []Deduction  // 这一轮推出来的，还没 accept
+	depth  int          // 第几轮
+	result Result
+}
+
+func (s *solver) run() error {
+	if !s.opt.Variant.classic() {
+		return s.runVariant()
+	}
+	if s.opt.Queue {
+		return s.runQueue()
+	}
+	v := s.view
+	for depth := 1; s.opt.MaxDepth == 0 || depth <= s.opt.MaxDepth; depth++ {
+		if err := v.Check(); err != nil {
+			return err
+		}
+		s.depth = depth
+		if s.opt.Rules&RuleBoom != 0 {
+			s.add(RuleBoom, true, v.FindBoom())
+		}
+		if s.opt.Rules&RuleNum != 0 {
+			s.add(RuleNum, false, v.FindNum())
+		}
+		if s.opt.Rules&RuleDiff != 0 {
+			boom, empty := v.FindDiff()
+			s.add(RuleDiff, true, boom)
+			s.add(RuleDiff, false, empty)
+		}
+		if err := s.global(); err != nil {
+			return err
+		}
+		changed, err := s.accept()
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// global 要看整个棋盘的规则
+func (s *solver) global() error {
+	v := s.view
+	if s.opt.Rules&RuleWa != 0 {
+		boom, empty := v.FindWa()
+		s.add(RuleWa, true, boom)
+		s.add(RuleWa, false, empty)
+	}
+	if s.opt.Rules&RulePattern != 0 {
+		for _, m := range v.FindPattern() {
+			n := len(s.found)
+			s.add(RulePattern, true, m.Mines)
+			s.add(RulePattern, false, m.Safe)
+			for i := n; i < len(s.found); i++ {
+				s.found[i].Pattern = m.Name
+			}
+		}
+	}
+	if s.opt.Rules&RuleCount != 0 && s.opt.Mines > 0 {
+		boom, empty, err := s.count()
+		if err != nil {
+			return err
+		}
+		s.add(RuleCount, true, boom)
+		s.add(RuleCount, false, empty)
+	}
+	if s.opt.Rules&RuleEnum != 0 {
+		boom, empty, err := s.enum()
+		if err != nil {
+			return err
+		}
+		s.add(RuleEnum, true, boom)
+		s.add(RuleEnum, false, empty)
+	}
+	return nil
+}
+
+// add 记下一条规则推出来的格子，已经不是没开的格子不要
+// 推出来过的格子还要，标成 cell.Marked 的雷后来又被推成不是雷的话，accept 才看得到矛盾
+func (s *solver) add(rule Rule, mine bool, list []*cell.Cell) {
+	for _, c := range list {
+		if c.IsUnknown() || s.done[c.Index(s.view.cols)] {
+			s.found = append(s.found, Deduction{Cell: c, Mine: mine, Rule: rule, Depth: s.depth})
+		}
+	}
+}
+
+// accept 把 s.found 里新的放进结果，雷马上标成 cell.Marked，返回变了的格子
+func (s *solver) accept() (changed []*cell.Cell, err error) {
+	v := s.view
+	found := s.found
+	s.found = s.found[:0]
+	for _, d := range found {
+		// 普通扫雷的规则只说是不是雷，雷数在这里补上，之后只看 Count
+		if d.Mine && d.Count == 0 {
+			d.Count = 1
+		}
+		d.Mine = d.Count > 0
+		index := d.Cell.Index(v.cols)
+		if s.done[index] {
+			if s.value[index] != d.Count {
+				return changed, fmt.Errorf("%w: %v 推出来 %v 个雷，之前是 %v 个", ErrContradiction, d.Cell, d.Count, s.value[index])
+			}
+			continue
+		}
+		s.done[index] = true
+		s.value[index] = d.Count
+		c := v.list[index]
+		changed = append(changed, c)
+		d.Cell = s.src.list[index]
+		if s.opt.Logger != nil {
+			pt := d.Cell.Pt()
+			s.opt.Logger.Debug("deduce", "row", pt.X, "col", pt.Y, "mine", d.Mine, "rule", d.Rule, "depth", d.Depth)
+		}
+		switch {
+		case d.Count > 0:
+			s.result.Mines = append(s.result.Mines, d)
+			if !c.IsMineKnown() {
+				v.SetState(c, cell.Marked)
+			}
+		case d.Count < 0:
+			// 还是没开的格子，雷数记在 s.value 里
+			s.result.Negative = append(s.result.Negative, d)
+		default:
+			s.safe[index] = true
+			s.result.Safe = append(s.result.Safe, d)
+		}
+	}
+	return changed, nil
+}
+
+// unknown 还没开、也还没推出来的格子
+func (s *solver) unknown(c *cell.Cell) bool {
+	return c.IsUnknown() && !s.safe[c.Index(s.view.cols)]
+}
+
+// count 剩下的雷和剩下的格子一样多，全是雷；剩下0个雷，全不是雷
+func (s *solver) count() (boom, empty []*cell.Cell, err error) {
+	flags := 0
+	var list []*cell.Cell
+	for _, c := range s.view.list {
+		if c.IsMineKnown() {
+			flags++
+		}
+		if s.unknown(c) {
+			list = append(list, c)
+		}
+	}
+	left := s.opt.Mines - flags
+	switch {
+	case left < 0 || left > len(list):
+		err = fmt.Errorf("%w: 还剩 %v 个雷，%v 个格子", ErrContradiction, left, len(list))
+	case left == 0:
+		empty = list
+	case left == len(list):
+		boom = list
+	}
+	return
+}
+
+// constraint 一个数字周围还要几个雷
+type constraint struct {
+	vars    []int // 周围没开的格子，是 enum 里 vars 的下标
+	weights []int // 每个格子的雷乘几，nil 是都乘1，只有变种里有
+	need    int
+}
+
+func (c constraint) weight(i int) int {
+	if c.weights == nil {
+		return 1
+	}
+	return c.weights[i]
+}
+
+// enum 边界上没开的格子按数字连起来分组，每组穷举所有可能的雷，
+// 所有可能里都是雷的就是雷，都不是雷的就不是雷
+func (s *solver) enum() (boom, empty []*cell.Cell, err error) {
+	vars, cons, err := s.frontier()
+	if err != nil {
+		return nil, nil, err
+	}
+	left := s.left()
+	s.result.Skipped = s.result.Skipped[:0]
+	for _, group := range groups(len(vars), cons) {
+		if len(group.vars) > s.opt.MaxEnum {
+			s.skip(vars, group)
+			continue
+		}
+		mines, total := group.solve(left)
+		if total == 0 {
+			return nil, nil, fmt.Errorf("%w: %v 附近怎么放雷都不对", ErrContradiction, vars[group.vars[0]])
+		}
+		for i, k := range group.vars {
+			switch mines[i] {
+			case total:
+				boom = append(boom, vars[k])
+			case 0:
+				empty = append(empty, vars[k])
+			}
+		}
+	}
+	return
+}
+
+// skip 记下没有穷举的一组
+func (s *solver) skip(vars []*cell.Cell, g *group) {
+	for _, k := range g.vars {
+		s.result.Skipped = append(s.result.Skipped, s.src.list[vars[k].Index(s.view.cols)])
+	}
+}
+
+// frontier 挨着数字的没开的格子，以及每个数字周围还要几个雷，constraint 里是 vars 的下标
+func (s *solver) frontier() (vars []*cell.Cell, cons []constraint, err error) {
+	v := s.view
+	varIndex := make(map[int]int)
+	for _, c := range v.list {
+		need, err := c.Num()
+		if err != nil {
+			continue
+		}
+		con := constraint{need: need}
+		for _, n := range v.Around(c) {
+			if n.IsMineKnown() {
+				con.need--
+				continue
+			}
+			if !s.unknown(n) {
+				continue
+			}
+			index := n.Index(v.cols)
+			k, ok := varIndex[index]
+			if !ok {
+				k = len(vars)
+				varIndex[index] = k
+				vars = append(vars, n)
+			}
+			con.vars = append(con.vars, k)
+		}
+		if con.need < 0 || con.need > len(con.vars) {
+			return nil, nil, fmt.Errorf("%w: %v 周围的雷对不上", ErrContradiction, c)
+		}
+		if len(con.vars) > 0 {
+			cons = append(cons, con)
+		}
+	}
+	return
+}
+
+// left 还剩几个雷没找到，不知道总雷数的时候是 -1
+func (s *solver) left() int {
+	if s.opt.Mines <= 0 {
+		return -1
+	}
+	left := s.opt.Mines
+	for i, c := range s.view.list {
+		if value, ok := s.value[i]; ok {
+			left -= value
+		} else if c.IsMineKnown() {
+			left--
+		}
+	}
+	return left
+}
+
+// group 互相有关联的格子和数字
+type group struct {
+	vars []int
+	cons []constraint // vars 换成了 group.vars 里的下标
+}
+
+// groups 用并查集把格子按数字连起来
+func groups(n int, cons []constraint) []*group {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	for _, con := range cons {
+		for _, k := range con.vars[1:] {
+			parent[find(k)] = find(con.vars[0])
+		}
+	}
+	byRoot := make(map[int]*group)
+	var list []*group
+	local := make([]int, n)
+	for k := 0; k < n; k++ {
+		root := find(k)
+		g, ok := byRoot[root]
+		if !ok {
+			g = &group{}
+			byRoot[root] = g
+			list = append(list, g)
+		}
+		local[k] = len(g.vars)
+		g.vars = append(g.vars, k)
+	}
+	for _, con := range cons {
+		g := byRoot[find(con.vars[0])]
+		c := constraint{need: con.need, weights: con.weights}
+		for _, k := range con.vars {
+			c.vars = append(c.vars, local[k])
+		}
+		g.cons = append(g.cons, c)
+	}
+	return list
+}
+
+// solve 回溯穷举，返回每个格子在多少种放法里是雷，以及一共多少种放法
+// left 大于等于0时，这一组的雷不能超过 left
+func (g *group) solve(left int) (mines []int, total int) {
+	mines = make([]int, len(g.vars))
+	g.each(left, []int{0, 1}, func(assign []int) {
+		total++
+		for i, a := range assign {
+			mines[i] += a
+		}
+	})
+	return
+}
+
+// each 回溯穷举，每个格子的雷数从 domain 里挑，每一种对的放法调用一次 f
+// left 大于等于0、而且没有负的雷时，这一组的雷不能超过 left
+func (g *group) each(left int, domain []int, f func(assign []int)) {
+	n := len(g.vars)
+	lo, hi := domain[0], domain[0]
+	for _, d := range domain {
+		if d < lo {
+			lo = d
+		}
+		if d > hi {
+			hi = d
+		}
+	}
+	if lo < 0 {
+		left = -1
+	}
+	assign := make([]int, n)
+	set := make([]bool, n)
+	// 每个格子在哪些数字里
+	in := make([][]int, n)
+	for ci, con := range g.cons {
+		for _, k := range con.vars {
+			in[k] = append(in[k], ci)
+		}
+	}
+	// 还没定的格子按最少和最多的雷数算，数字够不着就不对
+	ok := func(k int) bool {
+		for _, ci := range in[k] {
+			con := g.cons[ci]
+			sum, min, max := 0, 0, 0
+			for i, x := range con.vars {
+				w := con.weight(i)
+				switch {
+				case set[x]:
+					sum += w * assign[x]
+				case w > 0:
+					min, max = min+w*lo, max+w*hi
+				default:
+					min, max = min+w*hi, max+w*lo
+				}
+			}
+			if sum+min > con.need || sum+max < con.need {
+				return false
+			}
+		}
+		return true
+	}
+	var walk func(k, used int)
+	walk = func(k, used int) {
+		if left >= 0 && used > left {
+			return
+		}
+		if k == n {
+			f(assign)
+			return
+		}
+		set[k] = true
+		for _, a := range domain {
+			assign[k] = a
+			if ok(k) {
+				walk(k+1, used+a)
+			}
+		}
+		set[k] = false
+	}
+	walk(0, 0)
+}
+
+// Consequence WhatIf 推出来的结果
+type Consequence struct {
+	Boom  []*cell.Cell // 一定是雷
+	Empty []*cell.Cell // 一定不是雷
+}
+
+// WhatIf 假设格子 c 是 state，按规则一直推下去，看看能推出什么，或者哪里矛盾了
+// state 一般是 cell.Marked（是雷）或者一个数字（翻开是几），推完以后棋盘会恢复原样
+// 矛盾的时候返回的 error 是 ErrContradiction
+func (v *View) WhatIf(c *cell.Cell, state cell.State) (*Consequence, error) {
+	snapshot := v.Snapshot()
+	defer v.Restore(snapshot)
+
+	v.SetState(c, state)
+	ret, err := Solve(v, Options{InPlace: true})
+	consequence := &Consequence{}
+	for _, d := range ret.Mines {
+		consequence.Boom = append(consequence.Boom, d.Cell)
+	}
+	for _, d := range ret.Safe {
+		consequence.Empty = append(consequence.Empty, d.Cell)
+	}
+	return consequence, err
+}
+
+// Check 检查每个数字周围的雷对不对得上：旗子不能比数字多，旗子加上没开的不能比数字少
+func (v *View) Check() error {
+	for _, main := range v.list {
+		n, err := main.Num()
+		if err != nil {
+			continue
+		}
+		around := v.Around(main)
+		flag := len(v.filterFlag(around))
+		unknown := len(v.filterUnKnown(around))
+		if flag > n {
+			return fmt.Errorf("%w: %v 周围的旗子太多", ErrContradiction, main)
+		}
+		if flag+unknown < n {
+			return fmt.Errorf("%w: %v 周围的雷不够", ErrContradiction, main)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package view
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"taptap/biz/cell"
+)
+
+// textPitch Parse 出来的格子按这么多像素一格算中心点
+const textPitch = 45
+
+// Parse 从文本得到棋盘，方便测试和调试
+// 一行是棋盘的一行，一个字符是一个格子，和 cell.State 的 Byte 一样，空格会被忽略
+// 变种扫雷里不在 0-8 的数字写在方括号里，比如 [12] [-1]
+func Parse(text string) (*View, error) {
+	var list []*cell.Cell
+	nums := make(map[int]int)
+	cols := 0
+	row := 0
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.ReplaceAll(scanner.Text(), " ", "")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		col := 0
+		for i := 0; i < len(line); i++ {
+			state, err := cell.ParseState(line[i])
+			if line[i] == '[' {
+				end := strings.IndexByte(line[i:], ']')
+				if end < 0 {
+					return nil, fmt.Errorf("view: row %v: missing ]", row)
+				}
+				var n int
+				n, err = strconv.Atoi(line[i+1 : i+end])
+				state = cell.Opened
+				if 0 <= n && n <= 8 {
+					state = cell.State(n)
+				} else {
+					nums[len(list)] = n
+				}
+				i += end
+			}
+			if err != nil {
+				return nil, fmt.Errorf("view: row %v: %w", row, err)
+			}
+			list = append(list, cell.New(row, col, col*textPitch+textPitch/2, row*textPitch+textPitch/2, nil, state))
+			col++
+		}
+		if cols == 0 {
+			cols = col
+		}
+		if col != cols {
+			return nil, fmt.Errorf("view: row %v has %v cells, want %v", row, col, cols)
+		}
+		row++
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("view: empty board")
+	}
+	v := NewView(list, cols)
+	if len(nums) > 0 {
+		v.nums = nums
+	}
+	return v, nil
+}
+
+// String 和 Parse 对应的文本
+func (v *View) String() string {
+	var b strings.Builder
+	for i, c := range v.list {
+		if n, ok := v.nums[i]; ok && c.State() == cell.Opened {
+			fmt.Fprintf(&b, "[%d]", n)
+		} else {
+			b.WriteByte(c.Byte())
+		}
+		if (i+1)%v.cols == 0 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
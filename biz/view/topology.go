@@ -0,0 +1,127 @@
+package view
+
+import (
+	"fmt"
+
+	"taptap/biz/cell"
+)
+
+// Topology 一个格子周围是哪些格子，View 上所有的规则和求解都用它
+type Topology interface {
+	// Neighbors p 周围的格子，只返回棋盘里的，可以有重复，可以有 p 自己，View.Around 会去掉
+	Neighbors(p cell.Point, rows, cols int) []cell.Point
+}
+
+var (
+	kingMoves   = []cell.Point{{X: -1, Y: -1}, {X: -1, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: -1}, {X: 0, Y: 1}, {X: 1, Y: -1}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+	knightMoves = []cell.Point{{X: -2, Y: -1}, {X: -2, Y: 1}, {X: -1, Y: -2}, {X: -1, Y: 2}, {X: 1, Y: -2}, {X: 1, Y: 2}, {X: 2, Y: -1}, {X: 2, Y: 1}}
+	// 六边形按行错开，奇数行往右错半格
+	hexEven = []cell.Point{{X: -1, Y: -1}, {X: -1, Y: 0}, {X: 0, Y: -1}, {X: 0, Y: 1}, {X: 1, Y: -1}, {X: 1, Y: 0}}
+	hexOdd  = []cell.Point{{X: -1, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: -1}, {X: 0, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+)
+
+// Square 普通的扫雷，周围8个格子，出了棋盘就没有了
+type Square struct{}
+
+func (Square) Neighbors(p cell.Point, rows, cols int) []cell.Point {
+	return moves(p, rows, cols, kingMoves, false)
+}
+
+// Torus 周围8个格子，上下、左右连在一起
+type Torus struct{}
+
+func (Torus) Neighbors(p cell.Point, rows, cols int) []cell.Point {
+	return moves(p, rows, cols, kingMoves, true)
+}
+
+// Knight 周围是马走一步能到的8个格子
+type Knight struct{}
+
+func (Knight) Neighbors(p cell.Point, rows, cols int) []cell.Point {
+	return moves(p, rows, cols, knightMoves, false)
+}
+
+// Hex 六边形的格子，周围6个，文本里奇数行比偶数行往右错半格
+type Hex struct{}
+
+func (Hex) Neighbors(p cell.Point, rows, cols int) []cell.Point {
+	if p.X%2 == 0 {
+		return moves(p, rows, cols, hexEven, false)
+	}
+	return moves(p, rows, cols, hexOdd, false)
+}
+
+func moves(p cell.Point, rows, cols int, offsets []cell.Point, wrap bool) (list []cell.Point) {
+	for _, d := range offsets {
+		x, y := p.X+d.X, p.Y+d.Y
+		if wrap {
+			x, y = (x%rows+rows)%rows, (y%cols+cols)%cols
+		}
+		if 0 <= x && x < rows && 0 <= y && y < cols {
+			list = append(list, cell.Pt(x, y))
+		}
+	}
+	return
+}
+
+// ParseTopology square torus knight hex
+func ParseTopology(name string) (Topology, error) {
+	switch name {
+	case "", "square":
+		return Square{}, nil
+	case "torus":
+		return Torus{}, nil
+	case "knight":
+		return Knight{}, nil
+	case "hex":
+		return Hex{}, nil
+	}
+	return nil, fmt.Errorf("view: unknown topology %q", name)
+}
+
+// SetTopology 换一种格子之间的关系，nil 是 Square
+func (v *View) SetTopology(t Topology) {
+	v.topo = t
+}
+
+// Topology 现在用的格子之间的关系
+func (v *View) Topology() Topology {
+	if v.topo == nil {
+		return Square{}
+	}
+	return v.topo
+}
+
+// Around c 周围的格子，按 Topology 算，不含 c 自己，没有重复
+func (v *View) Around(c *cell.Cell) (list []*cell.Cell) {
+	for _, q := range v.Topology().Neighbors(c.Pt(), v.Rows(), v.Cols()) {
+		one := v.GetCell(q.X, q.Y)
+		if one == c || contains(list, one) {
+			continue
+		}
+		list = append(list, one)
+	}
+	return
+}
+
+// GetRel 和 c 周围有共同格子的格子，两个数字能不能一起推就看这个
+func (v *View) GetRel(c *cell.Cell) (list []*cell.Cell) {
+	for _, n := range v.Around(c) {
+		for _, one := range v.Around(n) {
+			if one == c || contains(list, one) {
+				continue
+			}
+			list = append(list, one)
+		}
+	}
+	return
+}
+
+func contains(list []*cell.Cell, c *cell.Cell) bool {
+	for _, one := range list {
+		if one == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package view
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"taptap/biz/cell"
+)
+
+func TestAround(t *testing.T) {
+	v, _ := Parse(strings.Repeat("____\n", 4))
+	tests := []struct {
+		topo Topology
+		x, y int
+		want int
+	}{
+		{Square{}, 0, 0, 3},
+		{Square{}, 1, 1, 8},
+		{Torus{}, 0, 0, 8},
+		{Knight{}, 0, 0, 2},
+		{Knight{}, 1, 1, 4},
+		{Hex{}, 1, 1, 6},
+		{Hex{}, 0, 0, 2},
+		{Hex{}, 1, 3, 3},
+	}
+	for _, tt := range tests {
+		v.SetTopology(tt.topo)
+		if got := v.Around(v.GetCell(tt.x, tt.y)); len(got) != tt.want {
+			t.Errorf("%T %v,%v: got %v", tt.topo, tt.x, tt.y, got)
+		}
+	}
+
+	// 2x2 的环面上下左右绕回来是同一个格子，不能重复
+	v, _ = Parse("__\n__")
+	v.SetTopology(Torus{})
+	if got := v.Around(v.GetCell(0, 0)); len(got) != 3 {
+		t.Fatalf("got %v", got)
+	}
+	if v.GetCell(2, 0) != nil || v.GetCell(0, -1) != nil {
+		t.Fatal("want nil outside the board")
+	}
+}
+
+func TestSolveTorus(t *testing.T) {
+	v, err := Parse(`
+		1011
+		0000
+		1011
+		101_
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Solve(v, Options{}); !errors.Is(err, ErrContradiction) {
+		t.Fatalf("square: want contradiction, got %v", err)
+	}
+	v.SetTopology(Torus{})
+	for _, queue := range []bool{false, true} {
+		ret, err := Solve(v, Options{Queue: queue})
+		if err != nil || len(ret.Mines) != 1 || !hasCell([]*cell.Cell{ret.Mines[0].Cell}, 3, 3) {
+			t.Fatalf("queue %v: got %v %v", queue, ret, err)
+		}
+	}
+}
+
+func TestSolveKnight(t *testing.T) {
+	v, err := Parse(`
+		100
+		00_
+		1_0
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SetTopology(Knight{})
+	for _, queue := range []bool{false, true} {
+		ret, err := Solve(v, Options{Queue: queue})
+		if err != nil || len(ret.Mines) != 1 || len(ret.Safe) != 1 {
+			t.Fatalf("queue %v: got %v %v", queue, ret, err)
+		}
+		if !hasCell([]*cell.Cell{ret.Mines[0].Cell}, 1, 2) || !hasCell([]*cell.Cell{ret.Safe[0].Cell}, 2, 1) {
+			t.Fatalf("queue %v: got %v %v", queue, ret.Mines, ret.Safe)
+		}
+	}
+}
+
+// 每种 Topology 上随机放雷，随机翻开一半不是雷的格子，推出来的都要对
+func TestSolveTopology(t *testing.T) {
+	for _, topo := range []Topology{Square{}, Torus{}, Knight{}, Hex{}} {
+		total := 0
+		for seed := int64(0); seed < 10; seed++ {
+			v, mine := topologyBoard(topo, 12, 16, 30, seed)
+			for _, queue := range []bool{false, true} {
+				ret, err := Solve(v, Options{Mines: 30, Queue: queue})
+				if err != nil {
+					t.Fatalf("%T seed %v: %v\n%v", topo, seed, err, v)
+				}
+				total += len(deductions(t, v, ret, mine))
+			}
+		}
+		if total == 0 {
+			t.Errorf("%T: nothing found", topo)
+		}
+	}
+}
+
+func topologyBoard(topo Topology, rows, cols, mines int, seed int64) (*View, []bool) {
+	rnd := rand.New(rand.NewSource(seed))
+	v, _ := Parse(strings.Repeat(strings.Repeat("_", cols)+"\n", rows))
+	v.SetTopology(topo)
+	mine := make([]bool, rows*cols)
+	for _, i := range rnd.Perm(rows * cols)[:mines] {
+		mine[i] = true
+	}
+	for _, i := range rnd.Perm(rows * cols) {
+		if mine[i] || rnd.Intn(2) == 0 {
+			continue
+		}
+		n := 0
+		for _, one := range v.Around(v.list[i]) {
+			if mine[one.Index(cols)] {
+				n++
+			}
+		}
+		v.SetState(v.list[i], cell.State(n))
+	}
+	return v, mine
+}
@@ -0,0 +1,60 @@
+package view
+
+import (
+	"taptap/biz/cell"
+)
+
+// change 一次修改，记下改之前的状态
+type change struct {
+	index int
+	old   cell.State
+}
+
+func (v *View) record(c *cell.Cell) {
+	v.log = append(v.log, change{c.Index(v.cols), c.State()})
+}
+
+// SetState 改一个格子的状态，会记到 undo 里
+// 要改格子都走 View，不要直接改 cell，不然没法 undo
+func (v *View) SetState(c *cell.Cell, state cell.State) {
+	v.record(c)
+	c.SetState(state)
+}
+
+// Snapshot 记下当前的位置，以后可以 Restore 回来
+func (v *View) Snapshot() int {
+	return len(v.log)
+}
+
+// Restore 把 Snapshot 之后的修改全部撤销
+func (v *View) Restore(snapshot int) {
+	for len(v.log) > snapshot {
+		v.Undo()
+	}
+}
+
+// Undo 撤销最近一次修改，没有可以撤销的返回 false
+func (v *View) Undo() bool {
+	if len(v.log) == 0 {
+		return false
+	}
+	last := v.log[len(v.log)-1]
+	v.log = v.log[:len(v.log)-1]
+	v.list[last.index].SetState(last.old)
+	return true
+}
+
+// Clone 复制一份棋盘，格子的状态各改各的，小图共用，只要 Close 一个
+func (v *View) Clone() *View {
+	list := make([]*cell.Cell, len(v.list))
+	for i, c := range v.list {
+		p := c.Pt()
+		list[i] = c.Move(p.X, p.Y)
+	}
+	return &View{
+		list: list,
+		cols: v.cols,
+		topo: v.topo,
+		nums: v.nums,
+	}
+}
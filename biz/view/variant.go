@@ -0,0 +1,170 @@
+package view
+
+import (
+	"fmt"
+
+	"taptap/biz/cell"
+)
+
+/*
+Variant 变种扫雷，普通扫雷的规则都假设一个格子要么有1个雷要么没有，数字就是周围有几个雷，
+变种里一个格子可以有好几个雷，或者有负的雷，数字数雷的时候也可以有的格子算两遍。
+
+变种只用 RuleEnum 的穷举：每个没开的格子的雷数从 Domain 里挑，
+每个数字要等于周围每个格子的 Weight 乘雷数加起来，所有放法里都一样的格子就推出来了。
+*/
+type Variant struct {
+	Domain []int                       // 一个没开的格子可能有几个雷，nil 是 {0, 1}
+	Weight func(num, c *cell.Cell) int // 数字 num 数格子 c 里的雷时乘几，nil 是都乘1
+}
+
+var (
+	// Double 一个格子最多两个雷
+	Double = &Variant{Domain: []int{0, 1, 2}}
+	// Negative 有的格子是负的雷，数字要减掉它
+	Negative = &Variant{Domain: []int{-1, 0, 1}}
+)
+
+// classic 是不是普通扫雷，nil 也是
+func (va *Variant) classic() bool {
+	if va == nil {
+		return true
+	}
+	if va.Weight != nil {
+		return false
+	}
+	return va.Domain == nil || (len(va.Domain) == 2 && va.Domain[0] == 0 && va.Domain[1] == 1)
+}
+
+func (va *Variant) domain() []int {
+	if va.Domain == nil {
+		return []int{0, 1}
+	}
+	return va.Domain
+}
+
+func (va *Variant) weight(num, c *cell.Cell) int {
+	if va.Weight == nil {
+		return 1
+	}
+	return va.Weight(num, c)
+}
+
+// Num 翻开的格子上的数字，变种里的大数字和负数也算，不是数字返回 false
+func (v *View) Num(c *cell.Cell) (int, bool) {
+	if c.State() == cell.Opened {
+		n, ok := v.nums[c.Index(v.cols)]
+		return n, ok
+	}
+	n, err := c.Num()
+	return n, err == nil
+}
+
+// runVariant 变种扫雷只用穷举，一轮一轮推到推不出新东西
+func (s *solver) runVariant() error {
+	for depth := 1; s.opt.MaxDepth == 0 || depth <= s.opt.MaxDepth; depth++ {
+		s.depth = depth
+		if err := s.enumVariant(); err != nil {
+			return err
+		}
+		changed, err := s.accept()
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// enumVariant 和 enum 一样分组穷举，所有放法里雷数都一样的格子推出来
+func (s *solver) enumVariant() error {
+	va := s.opt.Variant
+	vars, cons, err := s.frontierVariant()
+	if err != nil {
+		return err
+	}
+	left := s.left()
+	s.result.Skipped = s.result.Skipped[:0]
+	for _, group := range groups(len(vars), cons) {
+		if len(group.vars) > s.opt.MaxEnum {
+			s.skip(vars, group)
+			continue
+		}
+		n := len(group.vars)
+		values := make([]int, n)
+		fixed := make([]bool, n)
+		total := 0
+		group.each(left, va.domain(), func(assign []int) {
+			for i, a := range assign {
+				if total == 0 {
+					values[i], fixed[i] = a, true
+				} else if a != values[i] {
+					fixed[i] = false
+				}
+			}
+			total++
+		})
+		if total == 0 {
+			return fmt.Errorf("%w: %v 附近怎么放雷都不对", ErrContradiction, vars[group.vars[0]])
+		}
+		for i, k := range group.vars {
+			if fixed[i] {
+				s.found = append(s.found, Deduction{
+					Cell:  vars[k],
+					Mine:  values[i] > 0,
+					Count: values[i],
+					Rule:  RuleEnum,
+					Depth: s.depth,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// frontierVariant 和 frontier 一样，但是数字用 View.Num，已经推出来的格子按推出来的雷数减，
+// 屏幕上的旗子按1个雷算
+func (s *solver) frontierVariant() (vars []*cell.Cell, cons []constraint, err error) {
+	v := s.view
+	va := s.opt.Variant
+	varIndex := make(map[int]int)
+	for _, c := range v.list {
+		num, ok := v.Num(c)
+		if !ok {
+			continue
+		}
+		con := constraint{need: num}
+		for _, n := range v.Around(c) {
+			w := va.weight(c, n)
+			index := n.Index(v.cols)
+			if value, ok := s.value[index]; ok {
+				con.need -= w * value
+				continue
+			}
+			if n.IsMineKnown() {
+				con.need -= w
+				continue
+			}
+			if !s.unknown(n) || w == 0 {
+				continue
+			}
+			k, ok := varIndex[index]
+			if !ok {
+				k = len(vars)
+				varIndex[index] = k
+				vars = append(vars, n)
+			}
+			con.vars = append(con.vars, k)
+			con.weights = append(con.weights, w)
+		}
+		if len(con.vars) == 0 && con.need != 0 {
+			return nil, nil, fmt.Errorf("%w: %v 周围的雷对不上", ErrContradiction, c)
+		}
+		if len(con.vars) > 0 {
+			cons = append(cons, con)
+		}
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package view
+
+import (
+	"errors"
+	"testing"
+
+	"taptap/biz/cell"
+)
+
+func TestParseNums(t *testing.T) {
+	v, err := Parse(`
+		_[12]3
+		[-1]__
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Cols() != 3 || v.String() != "_[12]3\n[-1]__\n" {
+		t.Fatalf("got %q", v.String())
+	}
+	if n, ok := v.Num(v.GetCell(0, 1)); !ok || n != 12 || v.GetCell(0, 1).State() != cell.Opened {
+		t.Fatalf("got %v %v", n, ok)
+	}
+	if n, ok := v.Num(v.GetCell(0, 2)); !ok || n != 3 {
+		t.Fatalf("got %v %v", n, ok)
+	}
+	if _, ok := v.Num(v.GetCell(0, 0)); ok {
+		t.Fatal("unknown is not a number")
+	}
+	if _, err := Parse("_[3"); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestSolveVariant(t *testing.T) {
+	// 对角上的雷算1个，上下左右的算2个
+	cross := &Variant{Weight: func(num, c *cell.Cell) int {
+		if num.Pt().X == c.Pt().X || num.Pt().Y == c.Pt().Y {
+			return 2
+		}
+		return 1
+	}}
+	tests := []struct {
+		text    string
+		variant *Variant
+		mines   map[cell.Point]int
+		safe    []cell.Point
+	}{
+		{"_2", Double, map[cell.Point]int{cell.Pt(0, 0): 2}, nil},
+		{"_[3]_", Double, nil, nil},
+		{"___\n_[16]_\n___", Double, map[cell.Point]int{
+			cell.Pt(0, 0): 2, cell.Pt(0, 1): 2, cell.Pt(0, 2): 2, cell.Pt(1, 0): 2,
+			cell.Pt(1, 2): 2, cell.Pt(2, 0): 2, cell.Pt(2, 1): 2, cell.Pt(2, 2): 2,
+		}, nil},
+		{"_[-2]_", Negative, map[cell.Point]int{cell.Pt(0, 0): -1, cell.Pt(0, 2): -1}, nil},
+		{"_0_", Negative, nil, nil},
+		{"___\n_1_\n___", cross, map[cell.Point]int{}, []cell.Point{cell.Pt(0, 1), cell.Pt(1, 0), cell.Pt(1, 2), cell.Pt(2, 1)}},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret, err := Solve(v, Options{Variant: tt.variant})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.text, err)
+		}
+		// 雷数是负数的在 Negative 里，不算雷
+		got := append(append([]Deduction{}, ret.Mines...), ret.Negative...)
+		if len(got) != len(tt.mines) || len(ret.Safe) != len(tt.safe) {
+			t.Fatalf("%q: got %v %v %v", tt.text, ret.Mines, ret.Negative, ret.Safe)
+		}
+		for _, d := range got {
+			if n, ok := tt.mines[d.Cell.Pt()]; !ok || n != d.Count || d.Mine != (n > 0) || d.Rule != RuleEnum {
+				t.Fatalf("%q: got %v count %v", tt.text, d, d.Count)
+			}
+		}
+		for _, d := range ret.Mines {
+			if d.Count <= 0 {
+				t.Fatalf("%q: mine %v count %v", tt.text, d, d.Count)
+			}
+		}
+		for _, p := range tt.safe {
+			if !hasCell(cellsOf(ret.Safe), p.X, p.Y) {
+				t.Fatalf("%q: got safe %v", tt.text, ret.Safe)
+			}
+		}
+	}
+
+	// 普通的规则不认大数字，普通扫雷里 _2 是矛盾
+	v, _ := Parse("___\n_[16]_\n___")
+	if ret, err := Solve(v, Options{}); err != nil || len(ret.Mines)+len(ret.Safe) != 0 {
+		t.Fatalf("classic got %v %v", ret, err)
+	}
+	v, _ = Parse("_2")
+	if _, err := Solve(v, Options{}); !errors.Is(err, ErrContradiction) {
+		t.Fatalf("classic want contradiction, got %v", err)
+	}
+	v, _ = Parse("_[3]_")
+	if _, err := Solve(v, Options{Variant: Negative}); !errors.Is(err, ErrContradiction) {
+		t.Fatalf("want contradiction, got %v", err)
+	}
+
+	// 负的雷不标成 Marked，格子还是没开的
+	v, _ = Parse("_[-2]_")
+	if _, err := Solve(v, Options{Variant: Negative, InPlace: true}); err != nil || v.String() != "_[-2]_\n" {
+		t.Fatalf("got %q %v", v.String(), err)
+	}
+
+	// 变种只用穷举，给了别的规则或者 Queue 不是静悄悄地忽略掉
+	v, _ = Parse("_2")
+	for _, opt := range []Options{{Variant: Double, Rules: RuleBoom}, {Variant: Double, Queue: true}} {
+		if _, err := Solve(v, opt); err == nil {
+			t.Fatalf("%+v: want error", opt)
+		}
+	}
+	if _, err := Solve(v, Options{Variant: Double, Rules: RuleEnum}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 普通扫雷写成 Variant 也要和普通的规则推出来一样
+func TestSolveVariantClassic(t *testing.T) {
+	plain := &Variant{Domain: []int{0, 1}, Weight: func(num, c *cell.Cell) int { return 1 }}
+	for seed := int64(0); seed < 10; seed++ {
+		v, mine := randomBoard(9, 9, 10, seed)
+		a, err := Solve(v, Options{Mines: 10, Rules: RuleEnum})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Solve(v, Options{Mines: 10, Variant: plain})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deductions(t, v, a, mine)) != len(deductions(t, v, b, mine)) {
+			t.Fatalf("seed %v: classic %v %v variant %v %v", seed, a.Mines, a.Safe, b.Mines, b.Safe)
+		}
+	}
+}
+
+func cellsOf(list []Deduction) (cells []*cell.Cell) {
+	for _, d := range list {
+		cells = append(cells, d.Cell)
+	}
+	return
+}
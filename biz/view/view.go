@@ -6,9 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"image/color"
-	"math"
+	"io"
 
 	"taptap/biz/cell"
+	"taptap/biz/comb"
 
 	"gocv.io/x/gocv"
 )
@@ -17,6 +18,9 @@ import (
 type View struct {
 	list []*cell.Cell
 	cols int
+	log  []change    // 改过的格子，用来 undo
+	topo Topology    // 格子之间的关系，nil 是 Square
+	nums map[int]int // 变种扫雷里不在 0-8 的数字，格子是 cell.Opened
 }
 
 // NewView 给定一个cell的list和base，得到一个view
@@ -27,8 +31,18 @@ func NewView(list []*cell.Cell, base int) *View {
 	}
 }
 
+// Close 释放所有格子的小图
+func (v *View) Close() {
+	for _, c := range v.list {
+		c.Close()
+	}
+}
+
 // Rows 返回有多少行
 func (v *View) Rows() int {
+	if v.cols == 0 {
+		return 0
+	}
 	return len(v.list) / v.cols
 }
 
@@ -42,27 +56,18 @@ func (v *View) GetIndex(x, y int) int {
 	return cell.Index(x, y, v.cols)
 }
 
-// 根据x,y得到对应的cell
+// 根据x,y得到对应的cell，出了棋盘返回 nil
 func (v *View) GetCell(x, y int) *cell.Cell {
-	// todo 下标越界
+	if x < 0 || y < 0 || x >= v.Rows() || y >= v.Cols() {
+		return nil
+	}
 	index := v.GetIndex(x, y)
 	return v.list[index]
 }
 
-// GetSub 根据x,y得到这一个区域的9个格子
+// GetSub c 自己和 Around，c 在第一个
 func (v *View) GetSub(c *cell.Cell) (sub []*cell.Cell) {
-	for _, p := range c.Pt().GetSub() {
-		sub = append(sub, v.GetCell(p.X, p.Y))
-	}
-	return
-}
-
-// 给我一个x,y得到可以与之关联的12个格子的中心
-func (v *View) GetRel(c *cell.Cell) (sub []*cell.Cell) {
-	for _, p := range c.Pt().GetRel() {
-		sub = append(sub, v.GetCell(p.X, p.Y))
-	}
-	return
+	return append([]*cell.Cell{c}, v.Around(c)...)
 }
 
 func (v *View) showCell(src *gocv.Mat, cell *cell.Cell, red bool) {
@@ -82,10 +87,10 @@ func (v *View) Show3(src *gocv.Mat, boom, empty []*cell.Cell) {
 	}
 }
 
-func (v *View) Show() {
-	if len(v.list)%v.cols != 0 {
-		fmt.Println("col error")
-		return
+// Show 把棋盘带上行号列号写到 w
+func (v *View) Show(w io.Writer) error {
+	if v.cols == 0 || len(v.list)%v.cols != 0 {
+		return fmt.Errorf("view: %v cells do not fit %v cols", len(v.list), v.cols)
 	}
 
 	var buf bytes.Buffer
@@ -107,123 +112,121 @@ func (v *View) Show() {
 		buf.Write([]byte{cell.Byte()})
 		buf.Write([]byte{' '})
 	}
-	fmt.Println(buf.String())
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
 
-	return
+// show2Codes Show2 里每种格子的编号，0-8 就是数字，和以前一样，给读 Show2 的程序用，不能改
+var show2Codes = map[cell.State]int{
+	cell.Flag:      9,
+	cell.Question:  10,
+	cell.Unknown:   11,
+	cell.WrongFlag: 12,
+	cell.Mine:      13,
+	cell.Exploded:  14,
+	cell.Marked:    15,
 }
 
-func (v *View) Show2() {
-	dic := map[byte]int{
-		'0': 0,
-		'1': 1,
-		'2': 2,
-		'3': 3,
-		'4': 4,
-		'5': 5,
-		'6': 6,
-		'7': 7,
-		'8': 8,
-		'f': 9,
-		'?': 10,
-		'_': 11,
-	}
+// Show2 按行把所有格子的编号写到 w，json格式，编号见 show2Codes
+func (v *View) Show2(w io.Writer) error {
 	list := [][]int{}
 	tmp := []int{}
 	for _, c := range v.list {
-		b := c.Byte()
-		i, ok := dic[b]
+		code, ok := show2Codes[c.State()]
+		if n, err := c.Num(); err == nil {
+			code, ok = n, true
+		}
 		if !ok {
-			panic("show")
+			return fmt.Errorf("view: show2: %v has no code", c)
 		}
-		tmp = append(tmp, i)
+		tmp = append(tmp, code)
 		if len(tmp) == v.cols {
 			list = append(list, tmp)
 			tmp = []int{}
 		}
 	}
-	j, _ := json.Marshal(list)
-	fmt.Println(string(j))
+	return json.NewEncoder(w).Encode(list)
+}
+
+// Lost 是不是已经输了，输了的话说明原因
+func (v *View) Lost() (lost bool, why string) {
+	var boom, wrong, mine []*cell.Cell
+	for _, c := range v.list {
+		switch {
+		case c.IsExploded():
+			boom = append(boom, c)
+		case c.IsWrongFlag():
+			wrong = append(wrong, c)
+		case c.IsMine():
+			mine = append(mine, c)
+		}
+	}
+	if len(boom)+len(wrong)+len(mine) == 0 {
+		return false, ""
+	}
+	why = fmt.Sprintf("踩雷 %v，插错的旗子 %v，翻开的雷 %d 个", boom, wrong, len(mine))
+	return true, why
 }
 
 func (v *View) FindBoom() (boom []*cell.Cell) {
 	/*
 		有N个没开的格子，有N个雷，那么所有的格子都是雷
-		偏移量
-
-			1,1
-				-1,-1
 	*/
+	for _, main := range v.list {
+		n, err := main.Num()
+		if err != nil {
+			continue // 没开的格子
+		}
+		if n == 0 {
+			continue // 铁定没雷
+		}
 
-	for i := 1; i < v.Rows()-1; i++ {
-		for j := 1; j < v.Cols()-1; j++ {
-			main := v.GetCell(i, j)             // 中心
-			sub := v.GetSub(main)               // 9个格子
-			list := append(sub[:4], sub[5:]...) // 边缘
-
-			if main.IsUnTap() {
-				continue // 没开的格子
-			}
-			if main.Byte() == '0' {
-				continue // 铁定没雷
-			}
-
-			tmp := []*cell.Cell{}
-			num := 0
-			for _, cell := range list {
-				if cell.IsUnknown() {
-					tmp = append(tmp, cell)
-				}
-				if cell.IsUnTap() {
-					num++
-				}
+		tmp := []*cell.Cell{}
+		num := 0
+		for _, cell := range v.Around(main) {
+			if cell.IsUnknown() {
+				tmp = append(tmp, cell)
 			}
-			if num == main.Int() {
-				boom = append(boom, tmp...)
+			if cell.IsUnTap() {
+				num++
 			}
 		}
+		if num == n {
+			boom = append(boom, tmp...)
+		}
 	}
 	return boom
 }
 
 func (v *View) SetFlag(x, y int) {
 	cell := v.GetCell(x, y)
+	v.record(cell)
 	cell.SetFlag()
 }
 
 func (v *View) FindNum() (empty []*cell.Cell) {
 	/*
 		如果周围的雷和数字一致，剩余空间都不是雷
-		偏移量:
-
-			1,1
-				-1,-1
 	*/
-	for i := 1; i < v.Rows()-1; i++ {
-		for j := 1; j < v.Cols()-1; j++ {
-			main := v.GetCell(i, j)
-			sub := v.GetSub(main)
-			list := append(sub[:4], sub[5:]...) // 边缘
-
-			if main.IsUnTap() {
-				continue
-			}
-			if main.Byte() == '0' {
-				continue
-			}
-			num := 0
-			tmp := []*cell.Cell{}
-			for _, cell := range list {
-				if cell.IsFlag() {
-					num++
-				}
-				if cell.IsUnknown() {
-					tmp = append(tmp, cell)
-				}
+	for _, main := range v.list {
+		n, err := main.Num()
+		if err != nil || n == 0 {
+			continue
+		}
+		num := 0
+		tmp := []*cell.Cell{}
+		for _, cell := range v.Around(main) {
+			if cell.IsMineKnown() {
+				num++
 			}
-			if num == main.Int() {
-				empty = append(empty, tmp...)
+			if cell.IsUnknown() {
+				tmp = append(tmp, cell)
 			}
 		}
+		if num == n {
+			empty = append(empty, tmp...)
+		}
 	}
 	return empty
 }
@@ -234,27 +237,23 @@ func (v *View) FindDiff() (boom, empty []*cell.Cell) {
 		m-n<=C && B<=n && 0<=A<=n
 		当m-n=C时, 此时C全是雷，A全不是
 
-		因为mn要有交集, 所以偏移量会大一点
-
-			1,3
-			-3,-3
+		要有交集的两个数字才能相减，就是 GetRel 里的
+		GetRel 是对称的，一对数字只在前面那个那里算一次
 	*/
-	for i := 1; i < v.Rows()-3; i++ {
-		for j := 3; j < v.Cols()-3; j++ {
-			main := v.GetCell(i, j)
-			if main.IsUnTap() || main.Int() == 0 {
+	for _, main := range v.list {
+		if n, err := main.Num(); err != nil || n == 0 {
+			continue
+		}
+		for _, cell := range v.GetRel(main) {
+			if cell.Index(v.cols) < main.Index(v.cols) {
 				continue
 			}
-			list := v.GetRel(main)
-			for k := 1; k < len(list); k++ {
-				cell := list[k]
-				if cell.IsUnTap() || cell.Int() == 0 {
-					continue
-				}
-				tmpB, tmpE := v.diff(main, cell)
-				boom = append(boom, tmpB...)
-				empty = append(empty, tmpE...)
+			if n, err := cell.Num(); err != nil || n == 0 {
+				continue
 			}
+			tmpB, tmpE := v.diff(main, cell)
+			boom = append(boom, tmpB...)
+			empty = append(empty, tmpE...)
 		}
 	}
 	return
@@ -264,12 +263,16 @@ func (v *View) diff(cell1, cell2 *cell.Cell) (boom, empty []*cell.Cell) {
 	if cell1.Gt(cell2) {
 		cell1, cell2 = cell2, cell1
 	}
-	sub1 := v.GetSub(cell1)
-	sub2 := v.GetSub(cell2)
-	n := sub1[4].Int()
-	m := sub2[4].Int()
-	list1 := v.filterAround(sub1)
-	list2 := v.filterAround(sub2)
+	n, err := cell1.Num()
+	if err != nil {
+		return
+	}
+	m, err := cell2.Num()
+	if err != nil {
+		return
+	}
+	list1 := v.Around(cell1)
+	list2 := v.Around(cell2)
 	A := v.Sub(list1, list2)
 	C := v.Sub(list2, list1)
 	if m-n == len(C) {
@@ -307,6 +310,7 @@ func (v *View) Sub(list1, list2 []*cell.Cell) (sub []*cell.Cell) {
 
 func (v *View) Reset(x, y int) {
 	cell := v.GetCell(x, y)
+	v.record(cell)
 	cell.SetUnknown()
 }
 
@@ -330,14 +334,9 @@ func (v *View) And(list1, list2 []*cell.Cell) (and []*cell.Cell) {
 	return and
 }
 
-func (v *View) filterAround(a []*cell.Cell) (and []*cell.Cell) {
-	and = append(and, a[:4]...)
-	return append(and, a[5:]...)
-}
-
 func (v *View) filterFlag(sub []*cell.Cell) (and []*cell.Cell) {
 	for _, cell := range sub {
-		if cell.IsFlag() {
+		if cell.IsMineKnown() {
 			and = append(and, cell)
 		}
 	}
@@ -355,15 +354,9 @@ func (v *View) filterUnKnown(sub []*cell.Cell) (and []*cell.Cell) {
 }
 
 func (v *View) FindWa() (boom, empty []*cell.Cell) {
-	/*
-		偏移量
-
-			3,3
-			-3,-3
-	*/
 	//fmt.Println("s")
-	for i := 3; i < v.Rows()-3; i++ {
-		for j := 3; j < v.Cols()-3; j++ {
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
 			// i, j = 6, 5
 			sub := v.GetRelBig(i, j)
 			if len(sub) == 0 {
@@ -378,7 +371,8 @@ func (v *View) FindWa() (boom, empty []*cell.Cell) {
 
 			// 这个格子能挖，可以挖的新格子，放在sub里。
 			// 需要分别尝试挖一下
-			max := 3
+			// 每个能挖的格子至少占掉两个空白格子，所以最多挖 空白格子/2 个
+			max := len(v.filterUnKnown(v.Around(v.GetCell(i, j)))) / 2
 			if len(sub) < max {
 				max = len(sub)
 			}
@@ -404,39 +398,42 @@ func (v *View) Wa(i, j, w int, sub []*cell.Cell) (ret, boom []*cell.Cell) {
 	/*
 		给我一个格子i,j
 		挖w个位置，sub是这些位置的array
+		挖的时候一个一个挖，前几个已经挖不下去了，后面的组合就不用试了
 	*/
-
-	// 有这么多能挖的方案
 	main := v.GetCell(i, j)
-	list := v.Cmn(len(sub), w)
-	// fmt.Printf("有%d个方案\n", len(list))
-	for _, one := range list {
-		tmp := []*cell.Cell{}
-		tmp = append(tmp, main)
+	mainNum, err := main.Num()
+	if err != nil {
+		return
+	}
+	mainCount := mainNum - len(v.filterFlag(v.Around(main)))
+	mainList := v.filterUnKnown(v.Around(main))
+	c := comb.New(len(sub), w)
+	for c.Next() {
+		left := mainList
 		sum := 0
-		for index, b := range one {
-			if b {
-				c := sub[index]
-				s := v.filterFlag(v.filterAround(v.GetSub(c)))
-				sum += c.Int() - len(s)
-				tmp = append(tmp, c)
+		ok := true
+		for k, index := range c.Indices() {
+			one := sub[index]
+			n, err := one.Num()
+			if err == nil {
+				sum += n - len(v.filterFlag(v.Around(one)))
+				left, err = v.wa3(left, v.filterUnKnown(v.Around(one)))
+			}
+			if err != nil || mainCount < sum {
+				// 不是数字，溢出了，或者和前面挖的重了
+				c.Skip(k)
+				ok = false
+				break
 			}
 		}
-		mainCount := main.Int() - len(v.filterFlag(v.filterAround(v.GetSub(main))))
-		if mainCount < sum {
-			// 溢出了
-			continue
-		}
-
-		empty, err := v.wa2(tmp)
-		if err != nil {
+		if !ok {
 			continue
 		}
-		if len(empty) == mainCount-sum {
-			boom = append(boom, empty...)
+		if len(left) == mainCount-sum {
+			boom = append(boom, left...)
 		}
-		if mainCount == sum && len(empty) > 0 {
-			ret = append(ret, empty...)
+		if mainCount == sum && len(left) > 0 {
+			ret = append(ret, left...)
 		}
 	}
 	return
@@ -466,47 +463,11 @@ func (v *View) wa3(list, sub []*cell.Cell) (ret []*cell.Cell, err error) {
 	return
 }
 
-func (v *View) wa2(list []*cell.Cell) (ret []*cell.Cell, err error) {
-	// 0是被挖的这一个，省下的是要挖的
-	// 这里是挖挖看
-	main := list[0]
-	mainlist := v.GetSub(main)
-	mainlist = v.filterAround(mainlist)
-	mainlist = v.filterUnKnown(mainlist)
-	for i := 1; i < len(list); i++ {
-		cell := list[i]
-		sublist := v.GetSub(cell)
-		sublist = v.filterAround(sublist)
-		sublist = v.filterUnKnown(sublist)
-		mainlist, err = v.wa3(mainlist, sublist)
-		if err != nil {
-			return
-		}
-	}
-	return mainlist, nil
-}
-
-func (v *View) Count(x int) (count int, list []bool) {
-	for x > 0 {
-		var b bool
-		if x&0x1 == 1 {
-			count++
-			b = true
-		}
-		list = append(list, b)
-		x = (x >> 1)
-	}
-	return count, list
-}
-
+// Cmn 从m个里取n个的所有组合，每个组合是长度m的 mask
 func (v *View) Cmn(m, n int) (ret [][]bool) {
-	// 从m中取出n个数
-	// 我需要一个m位的二进制，这个数字将是
-	for i := 1; i < int(math.Pow(2, float64(m))); i++ {
-		c, l := v.Count(i)
-		if c == n {
-			ret = append(ret, l)
-		}
+	c := comb.New(m, n)
+	for c.Next() {
+		ret = append(ret, append([]bool(nil), c.Mask()...))
 	}
 	return
 }
@@ -524,6 +485,7 @@ func (v *View) GetRelBig(x, y int) []*cell.Cell {
 			3 4 5 6 7
 			- 8 9 a -
 
+		别的 Topology 下就是 GetRel 里的格子
 		但是实际中，有非常多的剪枝
 		- 如果两个没有空白格子的交集，不会挖
 		- 如果两个的交集只有1个，其实不会发生挖的情况
@@ -537,35 +499,26 @@ func (v *View) GetRelBig(x, y int) []*cell.Cell {
 		剪枝后的格子，只有两个，仅仅做取个就行。
 	*/
 	main := v.GetCell(x, y)
-	if main.IsUnTap() || main.Byte() == '0' {
-		// 如果没点开，或者是0，那就跳过
+	if main == nil {
+		// 出了棋盘
+		return nil
+	}
+	if n, err := main.Num(); err != nil || n == 0 {
+		// 没点开，或者是0，那就跳过
 		return nil
 	}
-	mainList := v.GetSub(main)
-	mainList = v.filterUnKnown(v.filterAround(mainList))
+	mainList := v.filterUnKnown(v.Around(main))
 	if len(mainList) < 5 {
 		// 最少要有5个空格子，才能这样分析
 		return nil
 	}
 	sub := []*cell.Cell{}
-	for offset := 0; offset < 25; offset++ {
-		rowOffset := offset/5 - 2
-		colOffset := offset%5 - 2
-		// 剪枝0,
-		c := rowOffset * colOffset
-		if c == 4 || c == -4 || (rowOffset == 0 && colOffset == 0) {
-			// 排除角落的4个, 排除自己
-			continue
-		}
-		row := x + rowOffset
-		col := y + colOffset
-		cell := v.GetCell(row, col)
-		if cell.IsUnTap() || cell.Byte() == '0' {
+	for _, cell := range v.GetRel(main) {
+		if n, err := cell.Num(); err != nil || n == 0 {
 			// 如果没点开，或者是0，那就跳过
 			continue
 		}
-		subList := v.GetSub(cell)
-		subList = v.filterUnKnown(v.filterAround(subList)) // sub的空白格子
+		subList := v.filterUnKnown(v.Around(cell)) // sub的空白格子
 		sub1 := v.And(mainList, subList)
 		// 剪枝1， 如果两个格子没交集，就continue
 		if len(sub1) == 0 {
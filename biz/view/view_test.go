@@ -1,7 +1,13 @@
 package view
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"taptap/biz/cell"
+
+	"golang.org/x/exp/slog"
 )
 
 func TestGet(t *testing.T) {
@@ -11,9 +17,271 @@ func TestGet(t *testing.T) {
 
 func TestCmn(t *testing.T) {
 	v := NewView(nil, 0)
-	v.Cmn(3, 2)
+	list := v.Cmn(3, 2)
+	if len(list) != 3 {
+		t.Fatalf("got %v", list)
+	}
+	for _, mask := range list {
+		if len(mask) != 3 {
+			t.Fatalf("got %v", list)
+		}
+	}
 	//for i := 1; i <= 7; i++ {
 	//c := v.Count(i)
 	//fmt.Println(i, c)
 	//}
 }
+
+func TestUndo(t *testing.T) {
+	v, err := Parse(`
+		1_
+		_2
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := v.Snapshot()
+	v.SetFlag(0, 1)
+	v.SetState(v.GetCell(1, 0), cell.State(3))
+	c := v.Clone()
+	if got := v.String(); got != "1f\n32\n" {
+		t.Fatalf("got %q", got)
+	}
+	if !v.Undo() || v.String() != "1f\n_2\n" {
+		t.Fatalf("undo got %q", v.String())
+	}
+	v.Restore(snapshot)
+	if v.String() != "1_\n_2\n" || v.Undo() {
+		t.Fatalf("restore got %q", v.String())
+	}
+	if c.String() != "1f\n32\n" {
+		t.Fatalf("clone got %q", c.String())
+	}
+}
+
+func TestWhatIf(t *testing.T) {
+	v, err := Parse(`
+		000000
+		012210
+		0____0
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 棋盘本身不能有矛盾，不然 WhatIf 报的矛盾不知道是谁的
+	if _, err := Solve(v, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	before := v.String()
+
+	// 2,2 是雷的话，1,1 的雷就够了，2,1 不是雷，再推下去 2,3 是雷，2,4 不是雷
+	ret, err := v.WhatIf(v.GetCell(2, 2), cell.Flag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Boom) != 1 || !hasCell(ret.Boom, 2, 3) {
+		t.Fatalf("got boom %v", ret.Boom)
+	}
+	if len(ret.Empty) != 2 || !hasCell(ret.Empty, 2, 1) || !hasCell(ret.Empty, 2, 4) {
+		t.Fatalf("got empty %v", ret.Empty)
+	}
+	if _, err := v.WhatIf(v.GetCell(2, 2), cell.State(0)); !errors.Is(err, ErrContradiction) {
+		t.Fatalf("want contradiction, got %v", err)
+	}
+	if v.String() != before {
+		t.Fatalf("board changed: %q", v.String())
+	}
+}
+
+func TestSolve(t *testing.T) {
+	v, err := Parse(`
+		000000
+		012210
+		0____0
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := v.String()
+	ret, err := Solve(v, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != before {
+		t.Fatalf("board changed: %q", v.String())
+	}
+	var mines, safe []*cell.Cell
+	for _, d := range ret.Mines {
+		if d.Rule == 0 || d.Depth < 1 {
+			t.Fatalf("no reason: %v", d)
+		}
+		mines = append(mines, d.Cell)
+	}
+	for _, d := range ret.Safe {
+		safe = append(safe, d.Cell)
+	}
+	if len(mines) != 2 || !hasCell(mines, 2, 2) || !hasCell(mines, 2, 3) {
+		t.Fatalf("got mines %v", ret.Mines)
+	}
+	if len(safe) != 2 || !hasCell(safe, 2, 1) || !hasCell(safe, 2, 4) {
+		t.Fatalf("got safe %v", ret.Safe)
+	}
+	for _, c := range mines {
+		if c != v.GetCell(c.Pt().X, c.Pt().Y) {
+			t.Fatalf("%v is not a cell of the input view", c)
+		}
+	}
+
+	// 老规则现在也用 Around，边上的也能推出来
+	ret, err = Solve(v, Options{Rules: RuleBoom | RuleNum | RuleDiff | RuleWa})
+	if err != nil || len(ret.Mines) != 2 || len(ret.Safe) != 2 {
+		t.Fatalf("got %v %v %v", ret.Mines, ret.Safe, err)
+	}
+
+	ret, err = Solve(v, Options{InPlace: true, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() == before || len(ret.Mines) != 2 {
+		t.Fatalf("got %q %v", v.String(), ret.Mines)
+	}
+}
+
+func TestSolveMines(t *testing.T) {
+	v, err := Parse(`
+		__
+		__
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := Solve(v, Options{Rules: RuleCount, Mines: 4})
+	if err != nil || len(ret.Mines) != 4 {
+		t.Fatalf("got %v %v", ret.Mines, err)
+	}
+	if _, err := Solve(v, Options{Mines: 5}); !errors.Is(err, ErrContradiction) {
+		t.Fatalf("want contradiction, got %v", err)
+	}
+}
+
+// 一组超过 MaxEnum 的话不穷举，要在 Skipped 里说出来
+func TestSolveSkipped(t *testing.T) {
+	v, err := Parse(`
+		1_
+		__
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := Solve(v, Options{Rules: RuleEnum, MaxEnum: 2})
+	if err != nil || len(ret.Mines)+len(ret.Safe) != 0 || len(ret.Skipped) != 3 {
+		t.Fatalf("got %v %v", ret, err)
+	}
+	ret, err = Solve(v, Options{Rules: RuleEnum, MaxEnum: 3})
+	if err != nil || len(ret.Skipped) != 0 {
+		t.Fatalf("got %v %v", ret, err)
+	}
+}
+
+// 同一个格子先推成雷再推成不是雷，或者反过来，都是矛盾，前后两轮推出来的也一样
+func TestSolveContradiction(t *testing.T) {
+	for _, mine := range []bool{true, false} {
+		for _, sameRound := range []bool{true, false} {
+			v, _ := Parse("1_\n__")
+			s := newSolver(v, Options{InPlace: true})
+			c := v.GetCell(1, 1)
+			s.depth = 1
+			s.add(RuleBoom, mine, []*cell.Cell{c})
+			if !sameRound {
+				if _, err := s.accept(); err != nil {
+					t.Fatal(err)
+				}
+				s.depth = 2
+			}
+			s.add(RuleNum, !mine, []*cell.Cell{c})
+			if _, err := s.accept(); !errors.Is(err, ErrContradiction) {
+				t.Fatalf("mine %v same round %v: got %v", mine, sameRound, err)
+			}
+		}
+	}
+}
+
+// 一对数字只算一次，同一个格子不会因为反过来再减一次推出来两遍
+func TestFindDiff(t *testing.T) {
+	v, err := Parse(`
+		____
+		1221
+		0000
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boom, empty := v.FindDiff()
+	if len(boom) != 2 || boom[0] == boom[1] || len(empty) != 0 {
+		t.Fatalf("got %v %v", boom, empty)
+	}
+	for _, c := range boom {
+		if p := c.Pt(); p != cell.Pt(0, 1) && p != cell.Pt(0, 2) {
+			t.Fatalf("got %v", boom)
+		}
+	}
+}
+
+func TestShow(t *testing.T) {
+	v, _ := Parse("1_\n_m")
+	var b strings.Builder
+	if err := v.Show(&b); err != nil || b.String() != "   0 1 \n0: 1 _ \n1: _ m \n" {
+		t.Fatalf("got %q %v", b.String(), err)
+	}
+	b.Reset()
+	if err := v.Show2(&b); err != nil || b.String() != "[[1,11],[11,15]]\n" {
+		t.Fatalf("got %q %v", b.String(), err)
+	}
+	if big, _ := Parse("[12]_"); big.Show2(&b) == nil {
+		t.Fatal("want error")
+	}
+	if err := NewView(v.list[:3], 2).Show(&b); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestSolveLogger(t *testing.T) {
+	v, _ := Parse("1_\n11")
+	var b strings.Builder
+	h := slog.HandlerOptions{Level: slog.LevelDebug}.NewTextHandler(&b)
+	if _, err := Solve(v, Options{Logger: slog.New(h)}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "msg=deduce row=0 col=1 mine=true rule=boom depth=1") {
+		t.Fatalf("got %q", b.String())
+	}
+}
+
+func hasCell(list []*cell.Cell, x, y int) bool {
+	for _, c := range list {
+		if c.Pt() == cell.Pt(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGuess(t *testing.T) {
+	v, err := Parse(`
+		2__
+		___
+		___
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 周围3个格子，每个 2/3 是雷，剩下5个格子分1个雷
+	c, p := Guess(v, Options{Mines: 3})
+	if c == nil || c.Pt() != cell.Pt(0, 2) || p < 0.19 || p > 0.21 {
+		t.Fatalf("got %v %v", c, p)
+	}
+	// 只剩2个雷的话都在2周围，别的格子一定不是雷
+	if _, p := Guess(v, Options{Mines: 2}); p != 0 {
+		t.Fatalf("got %v", p)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"time"
+
+	"taptap/biz/annotate"
+	"taptap/biz/view"
+	"taptap/img"
+
+	"gocv.io/x/gocv"
+)
+
+var debugDir = flag.String("debug", "", "把每一帧识别的每一步存到这个目录下按时间建的子目录里，打开 index.html 看")
+
+// dumper 把一帧识别的每一步存下来，最后写一个 index.html 按顺序列出来
+// 每一帧一个，识别的函数都带上它，nil 的时候什么都不做，没开 -debug 就是 nil
+type dumper struct {
+	dir   string
+	items []dumpItem
+}
+
+type dumpItem struct {
+	Name string
+	Note string
+	File string // 图片，和 Text 二选一
+	Text string
+}
+
+// newDumper 在 root 下面按时间建一个子目录，root 是空的返回 nil
+func newDumper(root string) *dumper {
+	if root == "" {
+		return nil
+	}
+	dir := filepath.Join(root, time.Now().Format("20060102-150405.000"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Error("debug", "err", err)
+		return nil
+	}
+	return &dumper{dir: dir}
+}
+
+// Save 存一张图，文件名前面加序号
+func (d *dumper) Save(name, note string, m gocv.Mat) {
+	if d == nil {
+		return
+	}
+	file := fmt.Sprintf("%02d-%v.png", len(d.items), name)
+	if !gocv.IMWrite(filepath.Join(d.dir, file), m) {
+		logger.Error("debug: write", "file", file)
+		return
+	}
+	d.items = append(d.items, dumpItem{Name: name, Note: note, File: file})
+}
+
+// Text 存一段文字，比如识别出来的棋盘
+func (d *dumper) Text(name, note, text string) {
+	if d == nil {
+		return
+	}
+	d.items = append(d.items, dumpItem{Name: name, Note: note, Text: text})
+}
+
+var indexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Dir}}</title></head>
+<body>
+<h1>{{.Dir}}</h1>
+{{range .Items}}
+<h2>{{.Name}}</h2>
+<p>{{.Note}}</p>
+{{if .File}}<a href="{{.File}}"><img src="{{.File}}" style="max-width:100%"></a>{{else}}<pre>{{.Text}}</pre>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// Close 写 index.html
+func (d *dumper) Close() {
+	if d == nil {
+		return
+	}
+	f, err := os.Create(filepath.Join(d.dir, "index.html"))
+	if err != nil {
+		logger.Error("debug", "err", err)
+		return
+	}
+	defer f.Close()
+	err = indexPage.Execute(f, struct {
+		Dir   string
+		Items []dumpItem
+	}{filepath.Base(d.dir), d.items})
+	if err != nil {
+		logger.Error("debug", "err", err)
+	}
+	logger.Info("debug", "index", filepath.Join(d.dir, "index.html"))
+}
+
+// Grid 找到的网格画在灰度图上
+func (d *dumper) Grid(gray gocv.Mat, x_list, y_list []int) {
+	if d == nil {
+		return
+	}
+	overlay := gocv.NewMat()
+	defer overlay.Close()
+	gocv.CvtColor(gray, &overlay, gocv.ColorGrayToBGR)
+	red := color.RGBA{255, 0, 0, 0}
+	for _, x := range x_list {
+		gocv.Line(&overlay, image.Pt(0, x), image.Pt(overlay.Cols(), x), red, 2)
+	}
+	for _, y := range y_list {
+		gocv.Line(&overlay, image.Pt(y, 0), image.Pt(y, overlay.Rows()), red, 2)
+	}
+	d.Save("grid", fmt.Sprintf("%v 条横线 %v，%v 条竖线 %v", len(x_list), x_list, len(y_list), y_list), overlay)
+}
+
+// Cells 和 getTar 的拼图一样，每个格子上面是缩放后的小图，下面是量化成两种颜色以后的
+func (d *dumper) Cells(v *view.View) {
+	if d == nil || v.Rows() == 0 {
+		return
+	}
+	const k, gap = 45, 5
+	montage := gocv.NewMatWithSize(gap+(2*k+gap)*v.Rows(), gap+(k+gap)*v.Cols(), gocv.MatTypeCV8UC3)
+	defer montage.Close()
+	for i := 0; i < v.Rows(); i++ {
+		for j := 0; j < v.Cols(); j++ {
+			c := v.GetCell(i, j)
+			if c.Mat() == nil || c.Mat().Empty() {
+				continue
+			}
+			x, y := gap+(k+gap)*j, gap+(2*k+gap)*i
+			small := img.TransformSize(*c.Mat(), k, 3)
+			f1, _ := img.ColorQuantization(small, 2)
+			top := montage.Region(image.Rect(x, y, x+k, y+k))
+			bottom := montage.Region(image.Rect(x, y+k, x+k, y+2*k))
+			small.CopyTo(&top)
+			f1.CopyTo(&bottom)
+			top.Close()
+			bottom.Close()
+			small.Close()
+			f1.Close()
+		}
+	}
+	d.Save("cells", "每个格子上面是小图，下面是量化成两种颜色的 f1", montage)
+}
+
+// Board 识别出来的棋盘，文字和画出来的都存
+func (d *dumper) Board(src gocv.Mat, v *view.View, x_list, y_list []int) {
+	if d == nil {
+		return
+	}
+	d.Text("board", "识别出来的棋盘", v.String())
+	r := &annotate.Result{Grid: annotate.Grid{Rows: x_list, Cols: y_list}, View: v}
+	m := annotate.Compare(src, r)
+	defer m.Close()
+	d.Save("compare", "左边是识别出来的，右边是截图", m)
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"math"
 
+	"taptap/biz/cell"
+
 	"gocv.io/x/gocv"
 	"golang.org/x/exp/slices"
 )
@@ -105,6 +107,9 @@ func DeleteColor(src gocv.Mat) (ret gocv.Mat) {
 	}
 	ret = gocv.NewMat()
 	gocv.Merge(bgr, &ret)
+	for k := range bgr {
+		bgr[k].Close()
+	}
 	return ret
 }
 
@@ -115,6 +120,8 @@ func DeleteColor(src gocv.Mat) (ret gocv.Mat) {
 // 原图是1600*720，但是他上边，下边什么的都不能用，必须要切除。
 //}
 
+// 这里的函数返回的 Mat 都是新建的，调用的人负责 Close，传进来的 Mat 不会被关掉
+
 // TransformColor 如果图是4通道的，就转成3通道
 func TransformColor(from gocv.Mat) (to gocv.Mat) {
 	if from.Type() == gocv.MatTypeCV8UC4 {
@@ -137,28 +144,46 @@ func TransformSize(from gocv.Mat, size, offset int) (to gocv.Mat) {
 	big := size + offset*2
 	roiFrom := image.Rect(offset, offset, l, l)
 
-	to = gocv.NewMatWithSize(l, l, gocv.MatTypeCV8UC3)
-	from = TransformColor(from)
-	gocv.Resize(from, &to, image.Point{big, big}, 0, 0, gocv.InterpolationArea)
-	to = to.Region(roiFrom)
-	return to
+	color := TransformColor(from)
+	defer color.Close()
+	resized := gocv.NewMatWithSize(l, l, gocv.MatTypeCV8UC3)
+	defer resized.Close()
+	gocv.Resize(color, &resized, image.Point{big, big}, 0, 0, gocv.InterpolationArea)
+	region := resized.Region(roiFrom)
+	defer region.Close()
+	return region.Clone()
+}
+
+// Diff 两张图平均每个像素每个通道差多少，大小或类型不一样就当作完全不同
+func Diff(a, b gocv.Mat) float64 {
+	if a.Rows() != b.Rows() || a.Cols() != b.Cols() || a.Type() != b.Type() {
+		return math.MaxFloat64
+	}
+	d := gocv.NewMat()
+	defer d.Close()
+	gocv.AbsDiff(a, b, &d)
+	m := d.Mean()
+	return (m.Val1 + m.Val2 + m.Val3 + m.Val4) / float64(a.Channels())
 }
 
 // ColorQuantization 用K种颜色重新画图,返回色板
 func ColorQuantization(src gocv.Mat, K int) (img gocv.Mat, count []ColorCount) {
 	// count = make(map[Color]int)
-	img = TransformColor(src)
-	img.ConvertTo(&img, gocv.MatTypeCV32F)
-	img = img.Reshape(1, img.Total())
+	data := TransformColor(src)
+	defer data.Close()
+	data.ConvertTo(&data, gocv.MatTypeCV32F)
+	samples := data.Reshape(1, data.Total())
+	defer samples.Close()
 
-	bestLabels := gocv.NewMat()
+	// 按亮度分好初始的类，只跑一次，同一张图每次结果都一样，多个协程同时跑也不受随机数影响
+	bestLabels := initLabels(samples, K)
 	defer bestLabels.Close()
 	criteria := gocv.NewTermCriteria(gocv.EPS+gocv.MaxIter, 100, 0.001)
-	attempts := 5
-	flags := gocv.KMeansRandomCenters
+	attempts := 1
+	flags := gocv.KMeansUseInitialLabels
 	centers := gocv.NewMat()
 	defer centers.Close()
-	gocv.KMeans(img, K, &bestLabels, criteria, attempts, flags, &centers)
+	gocv.KMeans(samples, K, &bestLabels, criteria, attempts, flags, &centers)
 
 	bestLabels.ConvertTo(&bestLabels, gocv.MatTypeCV8U) // 转一下，才能用后续的get方法
 	dic := make([]int, K)                               // 统计一下那个颜色多。我要找到第二多的颜色
@@ -167,7 +192,7 @@ func ColorQuantization(src gocv.Mat, K int) (img gocv.Mat, count []ColorCount) {
 		dic[ci]++
 		for j := 0; j < centers.Cols(); j++ {
 			bgr := centers.GetFloatAt(int(ci), j)
-			img.SetFloatAt(i, j, bgr)
+			samples.SetFloatAt(i, j, bgr)
 		}
 	}
 
@@ -186,54 +211,118 @@ func ColorQuantization(src gocv.Mat, K int) (img gocv.Mat, count []ColorCount) {
 		return a.Count > b.Count
 	})
 
-	img = img.Reshape(3, src.Rows())
-	img.ConvertTo(&img, gocv.MatTypeCV8UC3)
+	out := samples.Reshape(3, src.Rows())
+	defer out.Close()
+	img = gocv.NewMat()
+	out.ConvertTo(&img, gocv.MatTypeCV8UC3)
 	return
 }
 
+// initLabels 每个像素按亮度排序，平均分成K份作为kmeans的初始分类
+func initLabels(img gocv.Mat, K int) gocv.Mat {
+	n := img.Rows()
+	light := make([]float32, n)
+	order := make([]int, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < img.Cols(); j++ {
+			light[i] += img.GetFloatAt(i, j)
+		}
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) bool {
+		return light[a] < light[b]
+	})
+	labels := gocv.NewMatWithSize(n, 1, gocv.MatTypeCV32S)
+	for rank, i := range order {
+		labels.SetIntAt(i, 0, int32(rank*K/n))
+	}
+	return labels
+}
+
+// Classifier 识别一个格子的小图，模板匹配和训练出来的模型都用这个接口
+// 会在多个协程里同时调用
+type Classifier interface {
+	Classify(src gocv.Mat) cell.State
+}
+
+// Scorer 还能说出有多大把握的 Classifier，score 在 0-1 之间，越大越有把握
+type Scorer interface {
+	Score(src gocv.Mat) (state cell.State, score float64)
+}
+
 type Target struct {
 	img     gocv.Mat
 	imgList []gocv.Mat
 	bg      Color
 	color   Color
 	isNum   bool
-	num     int
+	label   Label
 }
 
-func NewTarget(img gocv.Mat) *Target {
+// NewTarget 用背景色和主色描述一个格子
+func NewTarget(bgColor, mainColor Color) *Target {
 	tar := &Target{
-		img: img,
+		bg:    bgColor,
+		color: mainColor,
 	}
+	tar.isNum = bgColor.IsDark()
 	return tar
 }
 
-//func (t *Target) SetNum(num int) {
-//	t.num = num
-//}
-//
-//func NewTarget(bgColor, mainColor Color) *Target {
-//	tar := &Target{
-//		bg:    bgColor,
-//		color: mainColor,
-//	}
-//	tar.isNum = bgColor.IsDark()
-//	return tar
-//}
-//
-//type TargetList []*Target
-//
-//func (tl TargetList) Check(tar *Target) (index int) {
-//	min := float64(500)
-//	for i, current := range tl {
-//		if tar.isNum != current.isNum {
-//			continue
-//		}
-//		f := tar.color.far(current.color)
-//		if f < min {
-//			min = f
-//			index = i
-//		}
-//	}
-//	return
-//}
-//
+// NewTargetFromImage 把一个格子的小图整理成45*45，取背景色和主色
+func NewTargetFromImage(src gocv.Mat) *Target {
+	ha := TransformSize(src, 45, 3)
+	defer ha.Close()
+	f1, dic := ColorQuantization(ha, 2)
+	defer f1.Close()
+	return NewTarget(dic[0].Color, dic[1].Color)
+}
+
+func (t *Target) SetLabel(label Label) {
+	t.label = label
+}
+
+func (t *Target) State() cell.State {
+	return t.label.State
+}
+
+type TargetList []*Target
+
+// Check 找颜色最接近的模板，背景深浅不一样的不比
+func (tl TargetList) Check(tar *Target) *Target {
+	ret, _ := tl.check(tar)
+	return ret
+}
+
+// maxFar 颜色差这么多就认为不像了
+const maxFar = 500
+
+func (tl TargetList) check(tar *Target) (*Target, float64) {
+	min := float64(maxFar)
+	var ret *Target
+	for _, current := range tl {
+		if tar.isNum != current.isNum {
+			continue
+		}
+		f := tar.color.far(current.color)
+		if f < min {
+			min = f
+			ret = current
+		}
+	}
+	if ret == nil {
+		return tl[0], maxFar
+	}
+	return ret, min
+}
+
+// Classify 模板匹配
+func (tl TargetList) Classify(src gocv.Mat) cell.State {
+	return tl.Check(NewTargetFromImage(src)).State()
+}
+
+// Score 颜色差得越少越有把握
+func (tl TargetList) Score(src gocv.Mat) (cell.State, float64) {
+	tar, far := tl.check(NewTargetFromImage(src))
+	return tar.State(), 1 - far/maxFar
+}
@@ -0,0 +1,150 @@
+package img
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+
+	"taptap/biz/cell"
+
+	"gocv.io/x/gocv"
+	"golang.org/x/exp/slices"
+)
+
+// featureSize 格子整理成45*45以后再缩成 featureSize*featureSize 当特征
+const featureSize = 9
+
+// Features 格子小图的特征，每个像素每个通道归一化到0-1
+func Features(src gocv.Mat) []float32 {
+	ha := TransformSize(src, 45, 3)
+	defer ha.Close()
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.Resize(ha, &small, image.Pt(featureSize, featureSize), 0, 0, gocv.InterpolationArea)
+	f := make([]float32, 0, featureSize*featureSize*3)
+	for i := 0; i < small.Rows(); i++ {
+		for j := 0; j < small.Cols(); j++ {
+			for _, c := range small.GetVecbAt(i, j) {
+				f = append(f, float32(c)/255)
+			}
+		}
+	}
+	return f
+}
+
+// KNN k近邻分类器，模型就是训练集里每个格子的特征
+type KNN struct {
+	K       int         `json:"k"`
+	Labels  []string    `json:"labels"`
+	Samples [][]float32 `json:"samples"`
+}
+
+func NewKNN(k int) *KNN {
+	if k < 1 {
+		k = 1
+	}
+	return &KNN{
+		K: k,
+	}
+}
+
+// LoadKNN 读取 train 写出来的模型文件
+func LoadKNN(filename string) (*KNN, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := &KNN{}
+	if err := json.Unmarshal(buf, m); err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	if len(m.Samples) == 0 || len(m.Samples) != len(m.Labels) {
+		return nil, fmt.Errorf("%v: %w", filename, errors.New("empty or broken model"))
+	}
+	if m.K < 1 {
+		return nil, fmt.Errorf("%v: k is %v, want at least 1", filename, m.K)
+	}
+	for _, name := range m.Labels {
+		if _, err := LabelByName(name); err != nil {
+			return nil, fmt.Errorf("%v: %w", filename, err)
+		}
+	}
+	return m, nil
+}
+
+// Save 写成 json
+func (m *KNN) Save(filename string) error {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf, 0644)
+}
+
+// Add 加一个标好的样本
+func (m *KNN) Add(src gocv.Mat, label Label) {
+	m.Samples = append(m.Samples, Features(src))
+	m.Labels = append(m.Labels, label.Name)
+}
+
+// Classify 和模板匹配一样的用法
+func (m *KNN) Classify(src gocv.Mat) cell.State {
+	return m.Predict(Features(src)).State
+}
+
+// Score 最多的票占所有票的多少
+func (m *KNN) Score(src gocv.Mat) (cell.State, float64) {
+	label, score := m.predict(Features(src))
+	return label.State, score
+}
+
+// Predict 找最近的K个样本投票，越近票越重
+func (m *KNN) Predict(f []float32) Label {
+	label, _ := m.predict(f)
+	return label
+}
+
+func (m *KNN) predict(f []float32) (Label, float64) {
+	type near struct {
+		dist  float64
+		label string
+	}
+	list := make([]near, 0, len(m.Samples))
+	for i, s := range m.Samples {
+		list = append(list, near{distance(f, s), m.Labels[i]})
+	}
+	slices.SortFunc(list, func(a, b near) bool {
+		return a.dist < b.dist
+	})
+	if len(list) > m.K {
+		list = list[:m.K]
+	}
+	votes := make(map[string]float64)
+	best := list[0].label
+	total := 0.0
+	for _, n := range list {
+		w := 1 / (n.dist + 1e-6)
+		votes[n.label] += w
+		total += w
+		if votes[n.label] > votes[best] {
+			best = n.label
+		}
+	}
+	label, _ := LabelByName(best)
+	return label, votes[best] / total
+}
+
+func distance(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return math.MaxFloat64
+	}
+	sum := float64(0)
+	for i := range a {
+		d := float64(a[i] - b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
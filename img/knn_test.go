@@ -0,0 +1,136 @@
+package img
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestFeatures(t *testing.T) {
+	src := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(10, 20, 30, 0), 52, 53, gocv.MatTypeCV8UC3)
+	defer src.Close()
+	f := Features(src)
+	if len(f) != featureSize*featureSize*3 {
+		t.Fatalf("got %v features", len(f))
+	}
+	for k, v := range f {
+		if want := float32(10*(k%3+1)) / 255; v != want {
+			t.Fatalf("feature %v: got %v, want %v", k, v, want)
+		}
+	}
+}
+
+func TestKNNPredict(t *testing.T) {
+	m := NewKNN(3)
+	m.Samples = [][]float32{{0, 0}, {0, 1}, {1, 0}, {5, 5}, {5, 6}}
+	m.Labels = []string{"0", "0", "1", "flag", "flag"}
+	for _, c := range []struct {
+		f    []float32
+		want string
+	}{
+		{[]float32{0.1, 0.1}, "0"},
+		{[]float32{5, 5.4}, "flag"},
+		// 最近的3个里 "0" 有两个，但是 "1" 近得多
+		{[]float32{0.98, 0}, "1"},
+	} {
+		if got := m.Predict(c.f); got.Name != c.want {
+			t.Fatalf("%v: got %v, want %v", c.f, got.Name, c.want)
+		}
+	}
+	if label, score := m.predict([]float32{5, 5.5}); label.Name != "flag" || score < 0.9 {
+		t.Fatalf("got %v %v", label.Name, score)
+	}
+}
+
+func TestKNNSave(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "model.json")
+	m := NewKNN(2)
+	m.Samples = [][]float32{{0.25, 0.5}, {1, 0}}
+	m.Labels = []string{"unknown", "boom"}
+	if err := m.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadKNN(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(m) {
+		t.Fatalf("got %v, want %v", got, m)
+	}
+
+	for k, buf := range []string{
+		`{"k":1,"labels":["1"],"samples":[]}`,
+		`{"k":1,"labels":["nine"],"samples":[[0]]}`,
+		`{"k":1`,
+		`{"k":0,"labels":["1"],"samples":[[0]]}`,
+		`{"k":-1,"labels":["1"],"samples":[[0]]}`,
+		`{"labels":["1"],"samples":[[0]]}`,
+	} {
+		broken := filepath.Join(dir, fmt.Sprintf("broken%v.json", k))
+		if err := os.WriteFile(broken, []byte(buf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKNN(broken); err == nil {
+			t.Fatalf("%v: want error", buf)
+		}
+	}
+	if _, err := LoadKNN(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+// testdata/corpus 是回归集，按 <标签名>/*.png 放，和 -eval 读的一样
+// 没开的、旗子和 0-3 是从 1.jpg 上切下来的，只放真的截图切出来的小图
+// 输了才会看到的4种还没有截图，tar 里是 tar/gen.go 画的模板，放进来就是拿模板认模板，截到了再补
+func TestCorpus(t *testing.T) {
+	var tl TargetList
+	m := NewKNN(1)
+	for _, f := range TarFiles {
+		src := gocv.IMRead(filepath.Join("..", "tar", "tar"+f.Name+".png"), gocv.IMReadColor)
+		if src.Empty() {
+			t.Fatalf("read tar%v", f.Name)
+		}
+		label, _ := LabelByName(f.Label)
+		tar := NewTargetFromImage(src)
+		tar.SetLabel(label)
+		tl = append(tl, tar)
+		m.Add(src, label)
+		src.Close()
+	}
+
+	dirs, err := os.ReadDir(filepath.Join("testdata", "corpus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, dir := range dirs {
+		label, err := LabelByName(dir.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, _ := filepath.Glob(filepath.Join("testdata", "corpus", dir.Name(), "*.png"))
+		for _, name := range files {
+			src := gocv.IMRead(name, gocv.IMReadColor)
+			if src.Empty() {
+				t.Fatalf("read %v", name)
+			}
+			for cls, got := range map[string]Label{
+				"template": LabelByState(tl.Classify(src)),
+				"knn":      LabelByState(m.Classify(src)),
+			} {
+				if got != label {
+					t.Errorf("%v %v: got %v", cls, name, got.Name)
+				}
+			}
+			src.Close()
+			n++
+		}
+	}
+	if n == 0 {
+		t.Fatal("empty corpus")
+	}
+}
@@ -0,0 +1,77 @@
+package img
+
+import (
+	"fmt"
+
+	"taptap/biz/cell"
+)
+
+// Label 一种格子的识别结果
+type Label struct {
+	Name  string     // 训练集里的目录名
+	State cell.State // 识别结果
+}
+
+// Labels 所有能识别的格子
+var Labels = []Label{
+	{"unknown", cell.Unknown},
+	{"flag", cell.Flag},
+	{"0", cell.State(0)},
+	{"1", cell.State(1)},
+	{"2", cell.State(2)},
+	{"3", cell.State(3)},
+	{"4", cell.State(4)},
+	{"5", cell.State(5)},
+	{"6", cell.State(6)},
+	{"7", cell.State(7)},
+	{"8", cell.State(8)},
+	{"question", cell.Question},
+	{"wrong", cell.WrongFlag},
+	{"mine", cell.Mine},
+	{"boom", cell.Exploded},
+}
+
+// TarFiles tar 目录里的模板文件名后缀对应的格子，没开的和旗子各有深浅两种底色
+// 后面4种只有输了才会看到，是 tar/gen.go 画的
+var TarFiles = []struct {
+	Name  string
+	Label string
+}{
+	{"-4", "unknown"},
+	{"-3", "unknown"},
+	{"-2", "flag"},
+	{"-1", "flag"},
+	{"0", "0"},
+	{"1", "1"},
+	{"2", "2"},
+	{"3", "3"},
+	{"4", "4"},
+	{"5", "5"},
+	{"6", "6"},
+	{"7", "7"},
+	{"8", "8"},
+	{"-question", "question"},
+	{"-wrong", "wrong"},
+	{"-mine", "mine"},
+	{"-boom", "boom"},
+}
+
+// LabelByName 按名字找标签
+func LabelByName(name string) (Label, error) {
+	for _, l := range Labels {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+	return Label{}, fmt.Errorf("unknown label %q", name)
+}
+
+// LabelByState 按识别结果找标签，没有的话当作没开的格子
+func LabelByState(state cell.State) Label {
+	for _, l := range Labels {
+		if l.State == state {
+			return l
+		}
+	}
+	return Labels[0]
+}
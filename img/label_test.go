@@ -0,0 +1,41 @@
+package img
+
+import (
+	"testing"
+
+	"taptap/biz/cell"
+)
+
+func TestLabelByName(t *testing.T) {
+	for _, l := range Labels {
+		got, err := LabelByName(l.Name)
+		if err != nil || got != l {
+			t.Fatalf("%v: got %v %v", l.Name, got, err)
+		}
+		if LabelByState(l.State) != l {
+			t.Fatalf("%v: got %v", l.State, LabelByState(l.State))
+		}
+	}
+	if _, err := LabelByName("nine"); err == nil {
+		t.Fatal("want error")
+	}
+	if LabelByState(cell.Marked) != Labels[0] {
+		t.Fatalf("got %v", LabelByState(cell.Marked))
+	}
+}
+
+// 每种格子都要有模板，不然模板匹配永远认不出来
+func TestTarFiles(t *testing.T) {
+	has := make(map[string]bool)
+	for _, f := range TarFiles {
+		if _, err := LabelByName(f.Label); err != nil {
+			t.Fatalf("%v: %v", f.Name, err)
+		}
+		has[f.Label] = true
+	}
+	for _, l := range Labels {
+		if !has[l.Name] {
+			t.Fatalf("no template for %v", l.Name)
+		}
+	}
+}
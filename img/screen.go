@@ -0,0 +1,157 @@
+package img
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// Screen 截图上是什么画面
+type Screen int
+
+const (
+	ScreenUnknown Screen = iota // 和哪个参考截图都不像
+	ScreenPlaying               // 正在玩
+	ScreenWon                   // 赢了的弹窗
+	ScreenLost                  // 输了的弹窗
+	ScreenDialog                // 广告或者别的弹窗挡住了棋盘
+)
+
+var screenNames = map[Screen]string{
+	ScreenUnknown: "unknown",
+	ScreenPlaying: "playing",
+	ScreenWon:     "won",
+	ScreenLost:    "lost",
+	ScreenDialog:  "dialog",
+}
+
+func (s Screen) String() string {
+	return screenNames[s]
+}
+
+const (
+	BoardTop    = 200 // 截图最上面这么多像素不是棋盘，和 get_x_list 跳过的一样
+	BoardBottom = 120 // 截图最下面这么多像素不是棋盘
+)
+
+// screenSize 上下两条缩成这么大当特征
+var screenSize = image.Pt(24, 6)
+
+// maxScreenDiff 每个像素每个通道平均差这么多灰度以内算同一个画面
+// 状态栏的时间和计时器每帧都在变，缩小以后平均下来差不了这么多，
+// 盖上弹窗以后变暗的那一层差得比这个多
+const maxScreenDiff = 26
+
+// defaultMaxDist 特征一共 2*24*6*3 个值，每个值都差 maxScreenDiff/255 时的距离，差不多是3
+var defaultMaxDist = maxScreenDiff / 255.0 * math.Sqrt(float64(2*screenSize.X*screenSize.Y*3))
+
+type screenRef struct {
+	screen   Screen
+	name     string
+	features []float32
+}
+
+// ScreenClassifier 在找网格之前先看看是什么画面
+// 只看截图上下两条不是棋盘的地方，和标好的参考截图比，找最像的那个
+type ScreenClassifier struct {
+	refs    []screenRef
+	MaxDist float64 // 最像的也比这个远，就是 ScreenUnknown，LoadScreens 按 maxScreenDiff 算
+}
+
+// LoadScreens 读取参考截图，文件名用画面的名字开头，比如 playing-1.png, lost.png
+func LoadScreens(dir string) (*ScreenClassifier, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return nil, err
+	}
+	sc := &ScreenClassifier{
+		MaxDist: defaultMaxDist,
+	}
+	for _, name := range files {
+		screen, ok := screenOf(filepath.Base(name))
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown screen", name)
+		}
+		src := gocv.IMRead(name, gocv.IMReadColor)
+		if src.Empty() {
+			src.Close()
+			return nil, fmt.Errorf("read %v", name)
+		}
+		// 和要判断的截图一样先去掉干扰的颜色
+		clean := DeleteColor(src)
+		sc.refs = append(sc.refs, screenRef{screen, name, screenFeatures(clean)})
+		clean.Close()
+		src.Close()
+	}
+	if len(sc.refs) == 0 {
+		return nil, fmt.Errorf("%v: %w", dir, os.ErrNotExist)
+	}
+	return sc, nil
+}
+
+func screenOf(base string) (Screen, bool) {
+	for s, name := range screenNames {
+		if s != ScreenUnknown && strings.HasPrefix(base, name) {
+			return s, true
+		}
+	}
+	return ScreenUnknown, false
+}
+
+// Classify 判断 DeleteColor 以后的截图是什么画面，返回最像的参考截图的文件名方便排查
+func (sc *ScreenClassifier) Classify(src gocv.Mat) (Screen, string) {
+	f := screenFeatures(src)
+	best := math.MaxFloat64
+	var ref screenRef
+	for _, r := range sc.refs {
+		if d := distance(f, r.features); d < best {
+			best = d
+			ref = r
+		}
+	}
+	if best > sc.MaxDist {
+		return ScreenUnknown, ref.name
+	}
+	return ref.screen, ref.name
+}
+
+// screenFeatures 截图最上面和最下面两条，各缩小以后拼起来
+func screenFeatures(src gocv.Mat) []float32 {
+	color := TransformColor(src)
+	defer color.Close()
+	rows, cols := color.Rows(), color.Cols()
+	var f []float32
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, cols, BoardTop),
+		image.Rect(0, rows-BoardBottom, cols, rows),
+	} {
+		f = append(f, stripFeatures(color, r.Intersect(image.Rect(0, 0, cols, rows)))...)
+	}
+	return f
+}
+
+func stripFeatures(src gocv.Mat, r image.Rectangle) []float32 {
+	f := make([]float32, screenSize.X*screenSize.Y*3)
+	if r.Empty() {
+		return f
+	}
+	region := src.Region(r)
+	defer region.Close()
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.Resize(region, &small, screenSize, 0, 0, gocv.InterpolationArea)
+	f = f[:0]
+	for i := 0; i < small.Rows(); i++ {
+		for j := 0; j < small.Cols(); j++ {
+			for _, c := range small.GetVecbAt(i, j) {
+				f = append(f, float32(c)/255)
+			}
+		}
+	}
+	return f
+}
@@ -0,0 +1,94 @@
+package img
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func uniformScreen(gray float64) gocv.Mat {
+	return gocv.NewMatWithSizeFromScalar(gocv.NewScalar(gray, gray, gray, 0), 1600, 720, gocv.MatTypeCV8UC3)
+}
+
+// 平均每个通道差不到 maxScreenDiff 的算同一个画面，超过了就是 ScreenUnknown
+func TestScreenMaxDist(t *testing.T) {
+	ref := uniformScreen(100)
+	defer ref.Close()
+	sc := &ScreenClassifier{
+		refs:    []screenRef{{ScreenPlaying, "playing.png", screenFeatures(ref)}},
+		MaxDist: defaultMaxDist,
+	}
+	for _, c := range []struct {
+		gray float64
+		want Screen
+	}{
+		{100, ScreenPlaying},
+		{100 + maxScreenDiff - 2, ScreenPlaying},
+		{100 - maxScreenDiff + 2, ScreenPlaying},
+		{100 + maxScreenDiff + 2, ScreenUnknown},
+	} {
+		src := uniformScreen(c.gray)
+		got, name := sc.Classify(src)
+		src.Close()
+		if got != c.want || name != "playing.png" {
+			t.Fatalf("gray %v: got %v %v, want %v", c.gray, got, name, c.want)
+		}
+	}
+}
+
+// screen 目录里的参考截图：每张都认得出自己，计时器变了还是正在玩，别的游戏的截图认不出来
+func TestLoadScreens(t *testing.T) {
+	sc, err := LoadScreens(filepath.Join("..", "screen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.refs) != 4 {
+		t.Fatalf("got %v refs", len(sc.refs))
+	}
+	classify := func(src gocv.Mat) Screen {
+		clean := DeleteColor(src)
+		defer clean.Close()
+		got, _ := sc.Classify(clean)
+		return got
+	}
+	for _, r := range sc.refs {
+		src := gocv.IMRead(r.name, gocv.IMReadColor)
+		if got := classify(src); got != r.screen {
+			t.Fatalf("%v: got %v", r.name, got)
+		}
+		src.Close()
+	}
+
+	src := gocv.IMRead(filepath.Join("..", "screen", "playing.png"), gocv.IMReadColor)
+	defer src.Close()
+	gocv.Rectangle(&src, image.Rect(270, 90, 450, 160), color.RGBA{90, 60, 60, 0}, -1)
+	if got := classify(src); got != ScreenPlaying {
+		t.Fatalf("timer changed: got %v", got)
+	}
+	green := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40, 120, 20, 0), 1600, 720, gocv.MatTypeCV8UC3)
+	defer green.Close()
+	if got := classify(green); got != ScreenUnknown {
+		t.Fatalf("green: got %v", got)
+	}
+}
+
+func TestLoadScreensError(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadScreens(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("empty dir: got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "menu.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadScreens(dir); err == nil {
+		t.Fatal("want error")
+	}
+	if s, ok := screenOf("won-2.png"); !ok || s != ScreenWon {
+		t.Fatalf("got %v %v", s, ok)
+	}
+}
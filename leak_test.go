@@ -0,0 +1,46 @@
+//go:build matprofile
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// 要带上 -tags matprofile 才会统计没关掉的 Mat
+func TestMatLeak(t *testing.T) {
+	empty, dic := getTar()
+	empty.Close()
+
+	frame := func() {
+		src, gray := getImage(filename)
+		defer src.Close()
+		defer gray.Close()
+		v := getView(src, gray, dic, nil)
+		v.Close()
+	}
+	r := newRecognizer(dic)
+	play := func() {
+		src, gray := getImage(filename)
+		defer src.Close()
+		defer gray.Close()
+		v := r.View(src, gray, nil)
+		v.Close()
+	}
+
+	frame()
+	play()
+	before := gocv.MatProfile.Count()
+	for i := 0; i < 20; i++ {
+		frame()
+		play()
+	}
+	if after := gocv.MatProfile.Count(); after != before {
+		var b bytes.Buffer
+		gocv.MatProfile.WriteTo(&b, 1)
+		t.Fatalf("mat count %v -> %v\n%s", before, after, b.String())
+	}
+	r.Close()
+}
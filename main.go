@@ -1,17 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"log"
 	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
 
+	"taptap/biz/annotate"
 	"taptap/biz/cell"
+	"taptap/biz/device"
+	"taptap/biz/plan"
+	"taptap/biz/report"
+	"taptap/biz/source"
+	"taptap/biz/stitch"
 	"taptap/biz/view"
 	"taptap/img"
 
 	"gocv.io/x/gocv"
+	"golang.org/x/exp/slog"
 )
 
 /*
@@ -22,8 +37,48 @@ var (
 	tarDir   = "./tar"
 	filename = "./1.jpg"
 	pi       = math.Pi
+
+	stitchMode = flag.Bool("stitch", false, "把平移时拍的多张截图拼成一个棋盘，截图按顺序放在参数里，或者用 -source 给目录、视频")
+	workers    = flag.Int("workers", runtime.NumCPU(), "同时识别格子的协程数")
+	modelFile  = flag.String("model", "", "用 train 训练出来的模型识别格子，不给就用 tar 模板匹配")
+	knnK       = flag.Int("k", 3, "train 时 kNN 的 k")
+	screenDir  = flag.String("screens", "./screen", "判断画面用的参考截图，文件名用 playing/won/lost/dialog 开头")
+	playMode   = flag.Bool("play", false, "一直用 adb 截图识别，格子没变就沿用上一帧的结果")
+	mines      = flag.Int("mines", 0, "总雷数，给了可以用剩下的雷数推理")
+	cascade    = flag.Bool("cascade", false, "周围没开的格子多的先点，不给就按手指移动最少的顺序点")
+	chord      = flag.Bool("chord", false, "旗子插够了的数字直接点数字，一次翻开周围的格子")
+	noFlag     = flag.Bool("noflag", false, "不插旗子，雷只记在程序里，省掉长按")
+	serial     = flag.String("serial", "", "adb -s，连了多台手机时用")
+	topology   = flag.String("topology", "square", "格子之间的关系，玩变种时用：square torus knight hex")
+	outFile    = flag.String("out", "", "识别一张截图时，把结果画成图存下来，比如 ret.png")
+	compare    = flag.Bool("compare", false, "-out 的图左边加上识别出来的棋盘，报 bug 用")
+	minScore   = flag.Float64("uncertain", 0.6, "识别的把握低于这个的格子在 -out 的图上标出来")
+	jsonOut    = flag.String("json", "", "把识别和求解的结果按 biz/report 的格式写成 json，一帧一行，- 是标准输出")
+	logLevel   = flag.String("log", "info", "日志级别：debug info warn error，debug 会记下每个推出来的格子")
+	logJSON    = flag.Bool("logjson", false, "日志写成 json，一条一行")
+	restartAt  = flag.String("restart", "", "-play 赢了或者输了以后点这里重新开一局，x,y 是截图上的像素，不给就停下来")
+	sourceName = flag.String("source", "", "截图从哪里来：adb adbraw - 目录 视频 图片，不给的话 -play 用 adb，不然读 ./1.jpg")
 )
 
+// logger 日志都写到标准错误，main 里按 -log 和 -logjson 重新设置
+var logger = slog.Default()
+
+// boardOut 棋盘打印到哪里，-json 写到标准输出的时候改成标准错误
+var boardOut io.Writer = os.Stdout
+
+// newLogger 按 -log 和 -logjson 生成 logger
+func newLogger() (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return nil, err
+	}
+	opt := slog.HandlerOptions{Level: level}
+	if *logJSON {
+		return slog.New(opt.NewJSONHandler(os.Stderr)), nil
+	}
+	return slog.New(opt.NewTextHandler(os.Stderr)), nil
+}
+
 func showIM(title string, src gocv.Mat) {
 	window := gocv.NewWindow(title)
 	defer window.Close()
@@ -48,14 +103,19 @@ func ColorQuantization(src gocv.Mat) (ret gocv.Mat, bgColor, mainColor img.Color
 	return
 }
 
-func getTarOne(i int) (a, b, c, d gocv.Mat, bg, mainColor img.Color) {
-	// 获取编号 i 的二值图, 顺手看下是不是数字
-	title := fmt.Sprintf(tarDir+"/tar%v.png", i)
-	small := gocv.IMRead(title, 1)
-	if small.Empty() {
+func tarFile(name string) string {
+	return fmt.Sprintf(tarDir+"/tar%v.png", name)
+}
+
+func getTarOne(name string) (a, b, c, d gocv.Mat, bg, mainColor img.Color) {
+	// 获取编号 name 的二值图, 顺手看下是不是数字
+	title := tarFile(name)
+	raw := gocv.IMRead(title, 1)
+	defer raw.Close()
+	if raw.Empty() {
 		log.Fatal("read tar")
 	}
-	small = img.TransformSize(small, 45, 3)
+	small := img.TransformSize(raw, 45, 3)
 
 	from, bg, mainColor := ColorQuantization(small) // BGR
 	gray := gocv.NewMat()
@@ -100,10 +160,17 @@ func getTar() (gocv.Mat, img.TargetList) {
 	//	8:  '8',
 	//}
 	var dic img.TargetList
-	empty := gocv.NewMatWithSize(5+50*5, 5+50*13, gocv.MatTypeCV8UC3)
+	var files []string
+	var labels []img.Label
+	for _, f := range img.TarFiles {
+		label, _ := img.LabelByName(f.Label)
+		files = append(files, f.Name)
+		labels = append(labels, label)
+	}
+	empty := gocv.NewMatWithSize(5+50*5, 5+50*len(files), gocv.MatTypeCV8UC3)
 	x := 5
-	for i := -4; i < 9; i++ {
-		aa, bb, cc, dd, bg, mainColor := getTarOne(i)
+	for i, name := range files {
+		aa, bb, cc, dd, bg, mainColor := getTarOne(name)
 		defer aa.Close()
 		defer bb.Close()
 		defer cc.Close()
@@ -126,6 +193,11 @@ func getTar() (gocv.Mat, img.TargetList) {
 		r2 := empty.Region(r[2])
 		r3 := empty.Region(r[3])
 		r4 := empty.Region(r[4])
+		defer r0.Close()
+		defer r1.Close()
+		defer r2.Close()
+		defer r3.Close()
+		defer r4.Close()
 
 		aa.CopyTo(&r0)
 		bb.CopyTo(&r1)
@@ -137,7 +209,7 @@ func getTar() (gocv.Mat, img.TargetList) {
 		//	color: mainColor,
 		//}
 		tar := img.NewTarget(bg, mainColor)
-		tar.SetNum(i)
+		tar.SetLabel(labels[i])
 		dic = append(dic, tar)
 		// fmt.Println("in tar", i, tar.isNum, tar.color)
 
@@ -146,11 +218,17 @@ func getTar() (gocv.Mat, img.TargetList) {
 	return empty, dic
 }
 
-func getImage() (src, gray gocv.Mat) {
-	src = gocv.IMRead(filename, gocv.IMReadUnchanged)
-	src = img.DeleteColor(src)
+func getImage(filename string) (src, gray gocv.Mat) {
+	raw := gocv.IMRead(filename, gocv.IMReadUnchanged)
+	defer raw.Close()
+	return cleanImage(raw)
+}
+
+// cleanImage 去掉干扰的颜色，顺便转一份灰度图
+func cleanImage(src gocv.Mat) (ret, gray gocv.Mat) {
+	ret = img.DeleteColor(src)
 	gray = gocv.NewMat()
-	gocv.CvtColor(src, &gray, gocv.ColorBGRToGray)
+	gocv.CvtColor(ret, &gray, gocv.ColorBGRToGray)
 	return
 }
 
@@ -162,56 +240,65 @@ func adaptiveThreshold(gray gocv.Mat) gocv.Mat {
 
 func getLine(dst gocv.Mat) (h, v, mask gocv.Mat) {
 	lineh := gocv.NewMat()
-	kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(40, 1)) // 40
-	gocv.Erode(dst, &lineh, kernel)
-	gocv.Dilate(lineh, &lineh, kernel)
+	kernelh := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(40, 1)) // 40
+	defer kernelh.Close()
+	gocv.Erode(dst, &lineh, kernelh)
+	gocv.Dilate(lineh, &lineh, kernelh)
 
 	linev := gocv.NewMat()
-	kernel = gocv.GetStructuringElement(gocv.MorphRect, image.Pt(1, 33))
-	gocv.Erode(dst, &linev, kernel)
-	gocv.Dilate(linev, &linev, kernel)
+	kernelv := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(1, 33))
+	defer kernelv.Close()
+	gocv.Erode(dst, &linev, kernelv)
+	gocv.Dilate(linev, &linev, kernelv)
 
 	line := gocv.NewMat()
 	gocv.Add(lineh, linev, &line)
 	return lineh, linev, line
 }
 
-func cropImage(xList, yList []int, src gocv.Mat, dic img.TargetList) (list []*cell.Cell) {
-	xStep := getStep(xList)
-	yStep := getStep(yList)
+func cropImage(xList, yList []int, src gocv.Mat, dic img.Classifier) (list []*cell.Cell) {
+	cols := len(yList) - 1
+	mats := make([]gocv.Mat, 0, (len(xList)-1)*cols)
+	centers := make([]image.Point, 0, cap(mats))
 	for i := 1; i < len(xList); i++ {
 		for j := 1; j < len(yList); j++ {
-			// i, j = 1, 5
-			// i = 5
-			// fmt.Println("===")
-			a := xList[i-1]
-			b := xList[i]
-			c := yList[j-1]
-			d := yList[j]
-			r := image.Rect(c, a, d, b)
-			s := src.Region(r)
-			t := s.Clone()
-			ret, retIndex := checkImage(t, dic)
-			cc := cell.New(
-				i-1,
-				j-1,
-				c+xStep,
-				a+yStep,
-				&t,
-				ret,
-				retIndex,
-			)
-			// fmt.Println(i-1, j-1, string([]byte{ret}), retIndex)
-
-			list = append(list, cc)
-			// fmt.Println(cc)
-			// showIM("haha", *(cc.Mat))
+			t, center := cropCell(xList, yList, src, i-1, j-1)
+			mats = append(mats, t)
+			centers = append(centers, center)
 		}
-		// return
+	}
+
+	states := make([]cell.State, len(mats))
+	parallel(len(mats), *workers, func(k int) {
+		states[k] = dic.Classify(mats[k])
+	})
+
+	for k := range mats {
+		cc := cell.New(
+			k/cols,
+			k%cols,
+			centers[k].X,
+			centers[k].Y,
+			&mats[k],
+			states[k],
+		)
+		// fmt.Println(cc)
+		list = append(list, cc)
 	}
 	return
 }
 
+// cropCell 按网格切出第 i 行第 j 列格子的小图，和它在截图里的中心点
+func cropCell(xList, yList []int, src gocv.Mat, i, j int) (gocv.Mat, image.Point) {
+	a := xList[i]
+	b := xList[i+1]
+	c := yList[j]
+	d := yList[j+1]
+	s := src.Region(image.Rect(c, a, d, b))
+	defer s.Close()
+	return s.Clone(), image.Pt(c+getStep(xList), a+getStep(yList))
+}
+
 func getStep(list []int) (step int) {
 	l := len(list)
 	step = (list[l-1] - list[0]) / l / 2
@@ -357,22 +444,27 @@ func showContours(src gocv.Mat, contours gocv.PointsVector, index int) {
 //	return minB, index
 //}
 
-func checkImage(src gocv.Mat, dic img.TargetList) (byte, int) {
-	// 要检查的图需要先整理成45*45大小
-	// showIM("check", img)
-	ha := img.TransformSize(src, 45, 3)
-	defer ha.Close()
-	f1, bg, mainColor := ColorQuantization(ha)
-	// fmt.Println(mainColor, isnum)
-	// showIM("img", img)
-	// showIM("img2", img2)
-	showIM("img3", f1)
-	// defer f1.Close()
-	// return min(dic, isnum, mainColor)
-	tar := img.NewTarget(bg, mainColor, 0)
-	index := dic.Check(tar)
-	tar.SetNum(index)
-	return ' ', 0
+// parallel 用 workers 个协程把 0 到 n-1 每个都跑一遍 fn，结果由 fn 按下标写回
+func parallel(n, workers int, fn func(k int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range ch {
+				fn(k)
+			}
+		}()
+	}
+	for k := 0; k < n; k++ {
+		ch <- k
+	}
+	close(ch)
+	wg.Wait()
 }
 
 func imgSaver(src gocv.Mat) {
@@ -391,15 +483,25 @@ func imgSaver(src gocv.Mat) {
 func x(src gocv.Mat) {
 	// showIM("t", src)
 	imgSaver(src)
-	src = src.Region(image.Rect(428, 575, 456, 615))
+	region := src.Region(image.Rect(428, 575, 456, 615))
+	defer region.Close()
 	// 看一个图里一共有多少颜色，画3个图
 	// gocv.Split(src)
 	empty1 := gocv.NewMatWithSize(255, 255, gocv.MatTypeCV8UC3)
 	empty2 := gocv.NewMatWithSize(255, 255, gocv.MatTypeCV8UC3)
 	empty3 := gocv.NewMatWithSize(255, 255, gocv.MatTypeCV8UC3)
-	src = img.TransformColor(src)
+	defer empty1.Close()
+	defer empty2.Close()
+	defer empty3.Close()
+	src = img.TransformColor(region)
+	defer src.Close()
 	fmt.Println(src.Type())
 	bgr := gocv.Split(src)
+	defer func() {
+		for k := range bgr {
+			bgr[k].Close()
+		}
+	}()
 	for j := 0; j < src.Cols(); j++ {
 		for i := 0; i < src.Rows(); i++ {
 			x := bgr[0].GetUCharAt(i, j)
@@ -421,13 +523,74 @@ func x(src gocv.Mat) {
 }
 
 func main() {
-	empty, dic := getTar()
+	flag.Parse()
+	if _, err := view.ParseTopology(*topology); err != nil {
+		log.Fatal(err)
+	}
+	lg, err := newLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger = lg
+	if *restartAt != "" {
+		if _, err := parsePoint(*restartAt); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *jsonOut != "" {
+		f, err := openReport(*jsonOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+	}
+	switch flag.Arg(0) {
+	case "train":
+		train(flag.Args()[1:])
+		return
+	case "eval":
+		eval(flag.Args()[1:])
+		return
+	case "bench":
+		bench(flag.Args()[1:])
+		return
+	case "puzzle":
+		checkPuzzle(flag.Args()[1:])
+		return
+	}
+	empty, tars := getTar()
 	defer empty.Close()
 	// showIM("tar", empty)
-	src, gray := getImage()
+	dic := getClassifier(tars)
+	if *stitchMode {
+		frames := stitchSource(flag.Args())
+		defer frames.Close()
+		stitchFrames(frames, dic)
+		return
+	}
+	screens := getScreens()
+	if *playMode {
+		play(dic, screens)
+		return
+	}
+	frames := openSource(filename)
+	defer frames.Close()
+	raw, err := frames.Next()
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, gray := cleanImage(raw)
+	raw.Close()
 	defer src.Close()
 	defer gray.Close()
+	if screen := getScreen(screens, src); screen != img.ScreenPlaying {
+		logger.Info("not playing", "screen", screen)
+		return
+	}
 
+	dump := newDumper(*debugDir)
+	defer dump.Close()
+	dump.Save("clean", "去掉干扰颜色以后的截图", src)
 	showIM("src", src)
 	// imgSaver(src)
 	// x(src)
@@ -438,34 +601,280 @@ func main() {
 	// showIM("gray", img2)
 	// return
 
+	start := time.Now()
+	x_list, y_list := getGrid(gray, dump)
+	v := view.NewView(cropImage(x_list, y_list, src, dic), len(y_list)-1)
+	recognize := time.Since(start)
+	dump.Cells(v)
+	dump.Board(src, v, x_list, y_list)
+	if err := v.Show2(boardOut); err != nil {
+		logger.Warn("show2", "err", err)
+	}
+	v.Show(boardOut)
+	defer v.Close()
+	if lost, why := v.Lost(); lost {
+		logger.Info("game over", "why", why)
+		return
+	}
+	start = time.Now()
+	ret, r := solveReport(v, x_list, y_list, dic, logger)
+	solve := time.Since(start)
+	boom1, empty1 := ret.Cells()
+	getPlan(v, boom1, empty1, logger)
+	if r != nil {
+		r.Time("recognize", recognize)
+		r.Time("solve", solve)
+		writeReport(r)
+	}
+	if *outFile != "" {
+		grid := annotate.Grid{Rows: x_list, Cols: y_list}
+		if err := writeResult(*outFile, src, v, grid, dic, boom1, empty1); err != nil {
+			logger.Error("write result", "file", *outFile, "err", err)
+		}
+	}
+	v.Show3(&src, boom1, empty1)
+	showIM("ret", src)
+	return
+}
+
+// getView 识别一张截图里能看到的棋盘，dump 是 nil 就不存中间结果
+func getView(src, gray gocv.Mat, dic img.Classifier, dump *dumper) *view.View {
+	x_list, y_list := getGrid(gray, dump)
+	cellList := cropImage(x_list, y_list, src, dic)
+	return view.NewView(cellList, len(y_list)-1)
+}
+
+// getGrid 找出网格线的位置
+func getGrid(gray gocv.Mat, dump *dumper) (x_list, y_list []int) {
 	dst := adaptiveThreshold(gray)
 	defer dst.Close()
-	showIM("dst", dst)
+	dump.Save("gray", "去掉干扰颜色以后的灰度图", gray)
+	dump.Save("threshold", "自适应二值化", dst)
 
 	lineh, linev, line := getLine(dst)
 	defer lineh.Close()
 	defer linev.Close()
 	defer line.Close()
-	x_list, _ := get_x_list(lineh)
+	dump.Save("lineh", "横线", lineh)
+	dump.Save("linev", "竖线", linev)
+	x_list, hist := get_x_list(lineh)
+	dump.Save("hist_x", fmt.Sprint("横线投影，找到 ", x_list), hist)
+	hist.Close()
 	x_list, xstep := solveStep(x_list)
 	// 上下到边了。
 
-	y_list, _ := get_y_list(linev)
+	y_list, hist = get_y_list(linev)
+	dump.Save("hist_y", fmt.Sprint("竖线投影，找到 ", y_list), hist)
+	hist.Close()
 	y_list, ystep := solveStep(y_list)
-	fmt.Println(xstep, ystep)
+	logger.Debug("grid", "rows", len(x_list)-1, "cols", len(y_list)-1, "xstep", xstep, "ystep", ystep)
 	// 左右到边了。
-	// g := drawGrid(x_list, y_list)
-	// showIM("g", g)
+	dump.Grid(gray, x_list, y_list)
+	return
+}
 
-	cellList := cropImage(x_list, y_list, src, dic)
+// play 一直截图识别求解，网格和没变的格子沿用上一帧
+func play(dic img.Classifier, screens *img.ScreenClassifier) {
+	r := newRecognizer(dic)
+	defer r.Close()
+	frames := openSource("adb")
+	defer frames.Close()
+	for playFrame(r, screens, frames.Next, playDevice(frames)) {
+	}
+}
 
-	v := view.NewView(cellList, len(y_list)-1)
-	v.Show2()
-	v.Show()
-	boom1, empty1 := finder(v)
-	v.Show3(&src, boom1, empty1)
-	showIM("ret", src)
-	return
+// playDevice 回放目录、视频里录下来的截图时，点了截图也不会变，不去点手机
+// 标准输入可能是 adb 一直在截图，还是要点
+func playDevice(frames source.FrameSource) device.Device {
+	switch frames.(type) {
+	case *source.Files, *source.Video:
+		logger.Info("offline source, taps are not sent", "source", *sourceName)
+		return device.Nop{}
+	}
+	return &device.ADB{Serial: *serial}
+}
+
+// openSource 按 -source 打开截图来源，没给就用 def
+func openSource(def string) source.FrameSource {
+	name := *sourceName
+	if name == "" {
+		name = def
+	}
+	frames, err := source.Open(name, *serial)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return frames
+}
+
+// playFrame 用 capture 截一帧，识别求解以后让 dev 去点，不用再玩了就返回 false
+// capture 返回 io.EOF 说明截图来源没有下一帧了，比如读完了目录或者视频
+func playFrame(r *recognizer, screens *img.ScreenClassifier, capture func() (gocv.Mat, error), dev device.Device) bool {
+	start := time.Now()
+	r.frame++
+	lg := logger.With("frame", r.frame)
+	raw, err := capture()
+	if errors.Is(err, io.EOF) {
+		lg.Info("no more frames")
+		return false
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, gray := cleanImage(raw)
+	raw.Close()
+	defer src.Close()
+	defer gray.Close()
+	dump := newDumper(*debugDir)
+	defer dump.Close()
+	dump.Save("clean", "去掉干扰颜色以后的截图", src)
+
+	switch screen := getScreen(screens, src); screen {
+	case img.ScreenPlaying:
+	case img.ScreenDialog:
+		// 弹窗挡住了棋盘，按返回键关掉
+		if err := dev.Back(); err != nil {
+			log.Fatal(err)
+		}
+		return true
+	case img.ScreenUnknown:
+		// 多半是动画还没放完，等一下再截
+		time.Sleep(time.Second)
+		return true
+	default:
+		lg.Info("game over", "screen", screen)
+		return restart(r, dev, lg)
+	}
+
+	v := r.View(src, gray, dump)
+	defer v.Close()
+	lg.Info("recognize", "took", time.Since(start))
+	dump.Cells(v)
+	dump.Board(src, v, r.xList, r.yList)
+	if lg.Enabled(context.Background(), slog.LevelDebug) {
+		v.Show(boardOut)
+	}
+	if lost, why := v.Lost(); lost {
+		lg.Info("game over", "why", why)
+		return restart(r, dev, lg)
+	}
+	solveStart := time.Now()
+	ret, rep := solveReport(v, r.xList, r.yList, r.dic, lg)
+	if rep != nil {
+		rep.Time("recognize", solveStart.Sub(start))
+		rep.Time("solve", time.Since(solveStart))
+		writeReport(rep)
+	}
+	boom, empty := ret.Cells()
+	actions, err := getPlan(v, boom, empty, lg)
+	if err != nil {
+		// 多半是这一帧识别错了，等一下重新截
+		time.Sleep(time.Second)
+		return true
+	}
+	if err := plan.Run(dev, actions); err != nil {
+		log.Fatal(err)
+	}
+	return true
+}
+
+// restart 给了 -restart 就点一下重新开一局，等动画放完接着玩，没给就不玩了
+func restart(r *recognizer, dev device.Device, lg *slog.Logger) bool {
+	if *restartAt == "" {
+		return false
+	}
+	p, err := parsePoint(*restartAt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lg.Info("restart", "at", p)
+	if err := dev.Tap(p); err != nil {
+		log.Fatal(err)
+	}
+	r.Reset()
+	time.Sleep(time.Second)
+	return true
+}
+
+// parsePoint 解析 x,y
+func parsePoint(s string) (image.Point, error) {
+	var p image.Point
+	if _, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y); err != nil {
+		return p, fmt.Errorf("point %q: want x,y: %w", s, err)
+	}
+	return p, nil
+}
+
+// getScreens 读取参考截图，没有的话就不判断画面
+func getScreens() *img.ScreenClassifier {
+	sc, err := img.LoadScreens(*screenDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return sc
+}
+
+// getScreen 找网格之前先看是什么画面，没有参考截图就当作正在玩
+func getScreen(sc *img.ScreenClassifier, src gocv.Mat) img.Screen {
+	if sc == nil {
+		return img.ScreenPlaying
+	}
+	screen, ref := sc.Classify(src)
+	logger.Debug("screen", "screen", screen, "ref", ref)
+	return screen
+}
+
+// stitchSource 给了 -source 就从那里读，不然按顺序读参数里的截图
+func stitchSource(files []string) source.FrameSource {
+	if *sourceName != "" {
+		return openSource("")
+	}
+	return source.NewFiles(files...)
+}
+
+// stitchFrames 大棋盘一屏放不下，把平移时拍的多张截图拼起来再求解，读到 io.EOF 为止
+func stitchFrames(in source.FrameSource, dic img.Classifier) {
+	var frames []*view.View
+	for {
+		raw, err := in.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		src, gray := cleanImage(raw)
+		raw.Close()
+		name := fmt.Sprint("frame ", len(frames)+1)
+		dump := newDumper(*debugDir)
+		dump.Save("clean", "去掉干扰颜色以后的截图", src)
+		v := getView(src, gray, dic, dump)
+		defer v.Close()
+		frames = append(frames, v)
+		dump.Cells(v)
+		dump.Text("board", name, v.String())
+		dump.Close()
+		src.Close()
+		gray.Close()
+	}
+	board, err := stitch.Stitch(frames, stitch.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, c := range board.Conflicts() {
+		logger.Warn("stitch conflict", "cell", c)
+	}
+	v := board.View()
+	v.Show(boardOut)
+	ret, rep := solveReport(v, nil, nil, dic, logger)
+	if rep != nil {
+		writeReport(rep)
+	}
+	boom, empty := ret.Cells()
+	getPlan(v, boom, empty, logger)
 }
 
 func solveStep(list []int) (tmp []int, step int) {
@@ -508,35 +917,125 @@ func solveStep(list []int) (tmp []int, step int) {
 	return
 }
 
-func finder(view *view.View) (boom, empty []*cell.Cell) {
-	boom1 := view.FindBoom()
-	boom = append(boom, boom1...)
+// finder 用 view.Solve 找出雷和一定不是雷的格子，雷在棋盘上标成 cell.Marked
+// 出错的时候 Result 里是出错之前推出来的，照样能用
+func finder(v *view.View, lg *slog.Logger) (*view.Result, error) {
+	topo, _ := view.ParseTopology(*topology) // main 里检查过了
+	v.SetTopology(topo)
+	ret, err := view.Solve(v, view.Options{Mines: *mines, InPlace: true, Queue: true, Logger: lg})
+	if err != nil {
+		lg.Warn("solve", "err", err)
+	}
+	lg.Info("solve", "mines", len(ret.Mines), "safe", len(ret.Safe))
+	return ret, err
+}
 
-	empty1 := view.FindNum()
-	empty = append(empty, empty1...)
+// writeResult 把识别和求解的结果画成图存下来，推不出来不是雷的格子时标上要猜的格子
+func writeResult(name string, src gocv.Mat, v *view.View, grid annotate.Grid, dic img.Classifier, boom, empty []*cell.Cell) error {
+	r := &annotate.Result{Grid: grid, View: v}
+	r.Add(annotate.Mine, boom...)
+	r.Add(annotate.Safe, empty...)
+	if len(empty) == 0 {
+		c, _ := view.Guess(v, view.Options{Mines: *mines})
+		r.Add(annotate.Guess, c)
+	}
+	if score := cellScore(dic); score != nil {
+		for i := 0; i < v.Rows(); i++ {
+			for j := 0; j < v.Cols(); j++ {
+				c := v.GetCell(i, j)
+				if s, ok := score(c); ok && s < *minScore {
+					r.Add(annotate.Uncertain, c)
+				}
+			}
+		}
+	}
+	var dst gocv.Mat
+	if *compare {
+		dst = annotate.Compare(src, r)
+	} else {
+		dst = annotate.Render(src, r)
+	}
+	defer dst.Close()
+	return annotate.Write(name, dst)
+}
 
-	boom2, empty2 := view.FindDiff()
-	boom = append(boom, boom2...)
-	empty = append(empty, empty2...)
+// cellScore 识别一个格子的把握，分类器给不出来返回 nil
+func cellScore(dic img.Classifier) func(c *cell.Cell) (float64, bool) {
+	scorer, ok := dic.(img.Scorer)
+	if !ok {
+		return nil
+	}
+	return func(c *cell.Cell) (float64, bool) {
+		if c.Mat() == nil {
+			return 0, false
+		}
+		_, score := scorer.Score(*c.Mat())
+		return score, true
+	}
+}
 
-	boom3, empty3 := view.FindWa()
-	boom = append(boom, boom3...)
-	empty = append(empty, empty3...)
+// reportOut -json 写到哪里，没给 -json 是 nil
+var reportOut io.Writer
 
-	if len(boom) > 0 {
-		for _, cell := range boom {
-			cell.SetFlag()
-		}
-		b, e := finder(view)
-		boom = append(boom, b...)
-		empty = append(empty, e...)
-		return
+// openReport 打开 -json 要写的文件，- 是标准输出
+func openReport(name string) (io.Closer, error) {
+	if name == "-" {
+		reportOut = os.Stdout
+		boardOut = os.Stderr
+		return io.NopCloser(nil), nil
 	}
-	if len(empty) > 0 {
-		for _, cell := range empty {
-			// todo 挖开这个格子
-			cell.Tap()
-		}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
 	}
-	return
+	reportOut = f
+	return f, nil
+}
+
+// solveReport 用 finder 求解，给了 -json 的话还返回这一帧的报告，没给是 nil
+// finder 会把推出来的雷标成 cell.Marked，报告里的格子要在求解之前记下识别出来的样子
+func solveReport(v *view.View, xList, yList []int, dic img.Classifier, lg *slog.Logger) (*view.Result, *report.Report) {
+	var r *report.Report
+	if reportOut != nil {
+		r = newReport(v, xList, yList, dic)
+	}
+	ret, err := finder(v, lg)
+	if r != nil {
+		r.Solved(ret, err)
+	}
+	return ret, r
+}
+
+// newReport 按 biz/report 的格式整理识别出来的棋盘，xList yList 是 nil 时没有网格线，比如拼起来的棋盘
+func newReport(v *view.View, xList, yList []int, dic img.Classifier) *report.Report {
+	r := report.New(v, xList, yList)
+	if score := cellScore(dic); score != nil {
+		r.Score(v, score)
+	}
+	return r
+}
+
+// writeReport 往 -json 的文件里写一行
+func writeReport(r *report.Report) {
+	if err := r.Write(reportOut); err != nil {
+		logger.Error("write report", "err", err)
+	}
+}
+
+// getPlan 把 finder 的结果排成要做的动作
+func getPlan(v *view.View, boom, empty []*cell.Cell, lg *slog.Logger) ([]plan.Action, error) {
+	opt := plan.Options{Chord: *chord, NoFlag: *noFlag}
+	if *cascade {
+		opt.Order = plan.OrderCascade
+	}
+	actions, err := plan.Plan(v, boom, empty, opt)
+	if err != nil {
+		lg.Warn("plan", "err", err)
+		return nil, err
+	}
+	for _, a := range actions {
+		pt := a.Cell.Pt()
+		lg.Info("action", "kind", a.Kind, "row", pt.X, "col", pt.Y)
+	}
+	return actions, nil
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"taptap/biz/cell"
+	"taptap/biz/device"
+	"taptap/biz/sim"
+	"taptap/biz/source"
+	"taptap/biz/view"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestCropImageDeterministic(t *testing.T) {
+	empty, dic := getTar()
+	defer empty.Close()
+	src, gray := getImage(filename)
+	defer src.Close()
+	defer gray.Close()
+	x_list, y_list := getGrid(gray, nil)
+
+	cols := len(y_list) - 1
+	first := cropImage(x_list, y_list, src, dic)
+	defer view.NewView(first, cols).Close()
+	for n := 0; n < 3; n++ {
+		list := cropImage(x_list, y_list, src, dic)
+		v := view.NewView(list, cols)
+		if len(list) != len(first) {
+			v.Close()
+			t.Fatalf("got %v cells, want %v", len(list), len(first))
+		}
+		for k, c := range list {
+			if c.Pt() != first[k].Pt() || c.State() != first[k].State() {
+				v.Close()
+				t.Fatalf("cell %v: got %v, want %v", k, c, first[k])
+			}
+		}
+		v.Close()
+	}
+}
+
+func benchmarkCropImage(b *testing.B, n int) {
+	empty, dic := getTar()
+	defer empty.Close()
+	src, gray := getImage(filename)
+	defer src.Close()
+	defer gray.Close()
+	x_list, y_list := getGrid(gray, nil)
+
+	old := *workers
+	*workers = n
+	defer func() { *workers = old }()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		view.NewView(cropImage(x_list, y_list, src, dic), len(y_list)-1).Close()
+	}
+}
+
+func BenchmarkCropImageSerial(b *testing.B) {
+	benchmarkCropImage(b, 1)
+}
+
+func BenchmarkCropImageParallel(b *testing.B) {
+	benchmarkCropImage(b, runtime.NumCPU())
+}
+
+// TestPlaySim 在模拟器上跑一遍截图、识别、求解、点击，不用手机
+func TestPlaySim(t *testing.T) {
+	tpl, err := sim.LoadTemplates(tarDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpl.Close()
+	empty, dic := getTar()
+	defer empty.Close()
+
+	g := sim.NewGame(18, 12, 30, 1)
+	s := sim.New(g, tpl)
+	s.Tap(s.Center(9, 6))
+
+	// 先看一帧识别得对不对
+	raw, _ := s.Screencap()
+	src, gray := cleanImage(raw)
+	raw.Close()
+	v := getView(src, gray, dic, nil)
+	src.Close()
+	gray.Close()
+	if v.Rows() != g.Rows() || v.Cols() != g.Cols() {
+		t.Fatalf("got %vx%v grid, want %vx%v", v.Rows(), v.Cols(), g.Rows(), g.Cols())
+	}
+	if got := v.String(); got != g.String() {
+		t.Fatalf("recognized\n%v\nwant\n%v", got, g)
+	}
+	v.Close()
+
+	r := newRecognizer(dic)
+	defer r.Close()
+	first := g.Opened()
+	for n := 0; n < 100 && !g.Won() && !g.Lost(); n++ {
+		opened, flags := g.Opened(), g.Flags()
+		if !playFrame(r, nil, s.Screencap, s) {
+			break
+		}
+		if g.Opened() == opened && g.Flags() == flags {
+			// 推不出来了，要猜
+			break
+		}
+	}
+	if g.Lost() {
+		t.Fatalf("lost\n%v\nanswer\n%v", g, g.Answer())
+	}
+	if g.Opened() <= first && !g.Won() {
+		t.Fatalf("no progress\n%v", g)
+	}
+}
+
+// TestJSONStdout -json - 的时候标准输出只能有报告，一行一个 JSON，棋盘要打到标准错误
+func TestJSONStdout(t *testing.T) {
+	tpl, err := sim.LoadTemplates(tarDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpl.Close()
+	empty, dic := getTar()
+	defer empty.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	oldLogger, oldBoard := logger, boardOut
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		logger, boardOut, reportOut = oldLogger, oldBoard, nil
+	}()
+	rOut, wOut, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rErr, wErr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = wOut, wErr
+	out, errOut := make(chan []byte), make(chan []byte)
+	go func() { b, _ := io.ReadAll(rOut); out <- b }()
+	go func() { b, _ := io.ReadAll(rErr); errOut <- b }()
+	// debug 级别 playFrame 才会打棋盘
+	logger = slog.New(slog.HandlerOptions{Level: slog.LevelDebug}.NewTextHandler(io.Discard))
+	if _, err := openReport("-"); err != nil {
+		t.Fatal(err)
+	}
+
+	g := sim.NewGame(18, 12, 30, 1)
+	s := sim.New(g, tpl)
+	s.Tap(s.Center(9, 6))
+	r := newRecognizer(dic)
+	defer r.Close()
+	playFrame(r, nil, s.Screencap, s)
+	stitchFrames(source.NewFiles(filename), dic)
+	wOut.Close()
+	wErr.Close()
+
+	lines := strings.Split(strings.TrimSpace(string(<-out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v lines on stdout, want 2 reports:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("not JSON on stdout: %q", line)
+		}
+	}
+	if len(<-errOut) == 0 {
+		t.Fatal("board not printed to stderr")
+	}
+}
+
+// TestSolveReport 报告里是识别出来的格子，推出来的雷不能变成 m
+func TestSolveReport(t *testing.T) {
+	defer func() { reportOut = nil }()
+	reportOut = io.Discard
+	v, err := view.Parse(`
+		1_
+		11
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, rep := solveReport(v, nil, nil, nil, logger)
+	if len(ret.Mines) != 1 || v.GetCell(0, 1).State() != cell.Marked {
+		t.Fatalf("got %v\n%v", ret.Mines, v)
+	}
+	if rep.Cells[1].State != cell.Unknown || len(rep.Mines) != 1 {
+		t.Fatalf("got %+v", rep)
+	}
+}
+
+func TestDebugDump(t *testing.T) {
+	empty, dic := getTar()
+	defer empty.Close()
+	src, gray := getImage(filename)
+	defer src.Close()
+	defer gray.Close()
+
+	dump := newDumper(t.TempDir())
+	dir := dump.dir
+	dump.Save("clean", "", src)
+	v := getView(src, gray, dic, dump)
+	defer v.Close()
+	dump.Cells(v)
+	dump.Close()
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"clean", "threshold", "lineh", "linev", "hist_x", "hist_y", "grid", "cells"} {
+		if !strings.Contains(string(index), "-"+name+".png") {
+			t.Errorf("index.html has no %v", name)
+		}
+	}
+}
+
+type tapRecorder struct {
+	taps []image.Point
+}
+
+func (d *tapRecorder) Tap(p image.Point) error {
+	d.taps = append(d.taps, p)
+	return nil
+}
+
+func (d *tapRecorder) LongPress(p image.Point) error { return nil }
+func (d *tapRecorder) Back() error                   { return nil }
+
+func TestPlayDevice(t *testing.T) {
+	if _, ok := playDevice(source.NewFiles(filename)).(device.Nop); !ok {
+		t.Fatal("files should not tap the phone")
+	}
+	if _, ok := playDevice(&source.ADB{}).(*device.ADB); !ok {
+		t.Fatal("adb should tap the phone")
+	}
+	if _, ok := playDevice(source.NewReader(strings.NewReader(""))).(*device.ADB); !ok {
+		t.Fatal("stdin may be a live phone")
+	}
+}
+
+func TestRestart(t *testing.T) {
+	r := newRecognizer(nil)
+	defer r.Close()
+	r.xList, r.yList = []int{0, 50}, []int{0, 50}
+	dev := &tapRecorder{}
+	if restart(r, dev, logger) || len(dev.taps) != 0 {
+		t.Fatalf("no -restart: got %v", dev.taps)
+	}
+
+	defer func(old string) { *restartAt = old }(*restartAt)
+	*restartAt = "60,1530"
+	if !restart(r, dev, logger) || len(dev.taps) != 1 || dev.taps[0] != image.Pt(60, 1530) {
+		t.Fatalf("got %v", dev.taps)
+	}
+	if r.xList != nil || r.yList != nil {
+		t.Fatalf("grid not reset: %v %v", r.xList, r.yList)
+	}
+	for _, s := range []string{"60", "a,b", ""} {
+		if _, err := parsePoint(s); err == nil {
+			t.Fatalf("%q: want error", s)
+		}
+	}
+}
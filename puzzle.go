@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"taptap/biz/puzzle"
+	"taptap/biz/view"
+)
+
+var suggest = flag.Bool("suggest", false, "puzzle 推不下去的时候，给出还要多翻开哪些格子")
+
+// checkPuzzle 检查题目能不能不靠猜推完，参数是题目的文本文件，格式见 puzzle.Parse
+func checkPuzzle(args []string) {
+	topo, _ := view.ParseTopology(*topology)
+	for _, name := range args {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		p, err := puzzle.Parse(string(data), topo)
+		if err != nil {
+			log.Fatalf("%v: %v", name, err)
+		}
+		r, err := p.Check()
+		if err != nil {
+			log.Fatalf("%v: %v", name, err)
+		}
+		fmt.Printf("%v\n%v", name, r)
+		if r.Solved || !*suggest {
+			continue
+		}
+		list, err := p.Suggest()
+		if err != nil {
+			log.Fatalf("%v: %v", name, err)
+		}
+		fmt.Printf("reveal %v more cells to make it solvable: %v\n", len(list), list)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"image"
+
+	"taptap/biz/cell"
+	"taptap/biz/view"
+	"taptap/img"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	cellDiff   = 8.0 // 小图平均每个像素差多少才算这个格子变了
+	gridBroken = 0.5 // 变了的格子超过这个比例，多半是拖动了棋盘，网格要重新找
+)
+
+// crop 一个格子的小图和识别结果
+type crop struct {
+	mat    gocv.Mat
+	center image.Point
+	state  cell.State
+}
+
+// recognizer 连续识别同一局的多帧截图
+// 每点一下只有几个格子会变，网格还能用就不重新找，
+// 每个格子的小图和上一帧比一下，没变就直接用上一帧的识别结果
+type recognizer struct {
+	dic   img.Classifier
+	size  []int // 截图大小，变了网格就要重新找
+	xList []int
+	yList []int
+	prev  []crop // 上一帧的格子，小图归 recognizer 所有
+	frame int    // 第几帧，记日志用
+}
+
+func newRecognizer(dic img.Classifier) *recognizer {
+	return &recognizer{
+		dic: dic,
+	}
+}
+
+// View 识别一帧，返回的 View 有自己的一份小图，用完要 Close
+// dump 不是 nil 的时候每帧都重新找网格，才能存下找网格的每一步
+func (r *recognizer) View(src, gray gocv.Mat, dump *dumper) *view.View {
+	if r.xList == nil || dump != nil || !sameSize(r.size, src.Size()) {
+		r.detect(src, gray, dump)
+	}
+	list := r.crop(src)
+	changed := r.changed(list)
+	count := 0
+	for _, ok := range changed {
+		if ok {
+			count++
+		}
+	}
+	if len(r.prev) > 0 && float64(count) > float64(len(list))*gridBroken {
+		closeCrops(list)
+		r.detect(src, gray, dump)
+		list = r.crop(src)
+		changed = r.changed(list)
+	}
+
+	parallel(len(list), *workers, func(k int) {
+		c := &list[k]
+		if changed[k] {
+			c.state = r.dic.Classify(c.mat)
+			return
+		}
+		c.state = r.prev[k].state
+	})
+	closeCrops(r.prev)
+	r.prev = list
+
+	cols := len(r.yList) - 1
+	cells := make([]*cell.Cell, 0, len(list))
+	for k := range list {
+		c := &list[k]
+		mat := c.mat.Clone()
+		cells = append(cells, cell.New(k/cols, k%cols, c.center.X, c.center.Y, &mat, c.state))
+	}
+	return view.NewView(cells, cols)
+}
+
+// Close 释放上一帧的小图
+func (r *recognizer) Close() {
+	closeCrops(r.prev)
+	r.prev = nil
+}
+
+// Reset 重新开了一局，棋盘大小可能变了，下一帧重新找网格
+func (r *recognizer) Reset() {
+	r.Close()
+	r.xList, r.yList = nil, nil
+}
+
+// detect 重新找网格，上一帧的格子对不上了，全部扔掉
+func (r *recognizer) detect(src, gray gocv.Mat, dump *dumper) {
+	r.size = src.Size()
+	r.xList, r.yList = getGrid(gray, dump)
+	r.Close()
+}
+
+// crop 按当前网格切出所有格子，还没有识别
+func (r *recognizer) crop(src gocv.Mat) (list []crop) {
+	for i := 0; i < len(r.xList)-1; i++ {
+		for j := 0; j < len(r.yList)-1; j++ {
+			var c crop
+			c.mat, c.center = cropCell(r.xList, r.yList, src, i, j)
+			list = append(list, c)
+		}
+	}
+	return
+}
+
+// changed 哪些格子和上一帧不一样，需要重新识别
+func (r *recognizer) changed(list []crop) []bool {
+	ret := make([]bool, len(list))
+	for k := range list {
+		ret[k] = len(r.prev) != len(list) || img.Diff(r.prev[k].mat, list[k].mat) > cellDiff
+	}
+	return ret
+}
+
+func closeCrops(list []crop) {
+	for k := range list {
+		list[k].mat.Close()
+	}
+}
+
+func sameSize(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"taptap/biz/cell"
+	"taptap/biz/sim"
+	"taptap/img"
+
+	"gocv.io/x/gocv"
+)
+
+// countClassifier 数一下识别了几个格子，看 recognizer 用没用上一帧的结果
+type countClassifier struct {
+	img.Classifier
+	n int64
+}
+
+func (c *countClassifier) Classify(src gocv.Mat) cell.State {
+	atomic.AddInt64(&c.n, 1)
+	return c.Classifier.Classify(src)
+}
+
+// TestRecognizer 一开始没开的棋盘连着喂两帧，看第二帧是沿用上一帧的格子还是重新找了网格
+// 重新找网格会扔掉上一帧的格子，所有格子都要重新识别，不然只识别变了的格子
+func TestRecognizer(t *testing.T) {
+	tpl, err := sim.LoadTemplates(tarDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpl.Close()
+	empty, dic := getTar()
+	defer empty.Close()
+
+	for _, tt := range []struct {
+		name     string
+		next     func(s *sim.Sim) // 第二帧之前改一下棋盘或者截图
+		dump     bool
+		detect   bool // 重新找了网格
+		classify int  // 没重新找网格时识别了几个格子
+	}{
+		{name: "same", next: func(s *sim.Sim) {}},
+		{name: "flag", next: flagCells(1), classify: 1},
+		{name: "many", next: flagCells(100), classify: 100},
+		{name: "broken", next: flagCells(150), detect: true}, // 18*12 的一半是 108
+		{name: "dump", next: func(s *sim.Sim) {}, dump: true, detect: true},
+		{name: "size", next: func(s *sim.Sim) { s.Width -= 20 }, detect: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g := sim.NewGame(18, 12, 30, 1)
+			s := sim.New(g, tpl)
+			c := &countClassifier{Classifier: dic}
+			r := newRecognizer(c)
+			defer r.Close()
+
+			frame := func(d *dumper) {
+				raw, _ := s.Screencap()
+				src, gray := cleanImage(raw)
+				raw.Close()
+				defer src.Close()
+				defer gray.Close()
+				r.View(src, gray, d).Close()
+			}
+			frame(nil)
+			if len(r.xList) != g.Rows()+1 || len(r.yList) != g.Cols()+1 {
+				t.Fatalf("got %vx%v grid", len(r.xList)-1, len(r.yList)-1)
+			}
+			atomic.StoreInt64(&c.n, 0)
+
+			tt.next(s)
+			var d *dumper
+			if tt.dump {
+				d = newDumper(t.TempDir())
+				defer d.Close()
+			}
+			frame(d)
+			want := tt.classify
+			if tt.detect {
+				want = g.Rows() * g.Cols()
+			}
+			if got := atomic.LoadInt64(&c.n); got != int64(want) {
+				t.Fatalf("classified %v cells, want %v", got, want)
+			}
+		})
+	}
+}
+
+// flagCells 一行一行在前 n 个格子上插旗子，只有这 n 格变了
+func flagCells(n int) func(s *sim.Sim) {
+	return func(s *sim.Sim) {
+		for k := 0; k < n; k++ {
+			s.LongPress(s.Center(k/s.Cols(), k%s.Cols()))
+		}
+	}
+}
@@ -1,3 +1,3 @@
 #!/bin/bash
-adb shell screencap -p >1.jpg
-./main
+# 直接用 adb 截图，不用先存成 1.jpg；截图慢的话换成 -source adbraw
+./main -source adb "$@"
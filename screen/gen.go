@@ -0,0 +1,79 @@
+//go:build ignore
+
+// 画出判断画面用的参考截图，在仓库根目录跑 go run screen/gen.go
+// playing 就是 1.jpg；赢了、输了和弹窗手上还没有截图，先在 1.jpg 上盖一层半透明的黑色，
+// 中间画一个白色的弹窗，赢了和输了在上面再加一条不同颜色的横幅。截到真的图以后换掉就行
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+)
+
+var (
+	card   = color.RGBA{250, 250, 250, 255}
+	gold   = color.RGBA{250, 200, 60, 255}
+	red    = color.RGBA{200, 60, 70, 255}
+	banner = image.Rect(0, 40, 720, 190)
+	popup  = image.Rect(80, 500, 640, 1100)
+)
+
+func main() {
+	f, err := os.Open("1.jpg")
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	save("screen/playing.png", toRGBA(src))
+
+	dialog := dim(src)
+	fillRect(dialog, popup, card)
+	save("screen/dialog.png", dialog)
+
+	for name, c := range map[string]color.RGBA{"won": gold, "lost": red} {
+		m := dim(src)
+		fillRect(m, popup, card)
+		fillRect(m, banner, c)
+		save("screen/"+name+".png", m)
+	}
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	m := image.NewRGBA(src.Bounds())
+	draw.Draw(m, m.Bounds(), src, src.Bounds().Min, draw.Src)
+	return m
+}
+
+// dim 弹窗后面的画面变暗，只剩四成亮度
+func dim(src image.Image) *image.RGBA {
+	m := toRGBA(src)
+	for k := range m.Pix {
+		if k%4 != 3 {
+			m.Pix[k] = uint8(int(m.Pix[k]) * 4 / 10)
+		}
+	}
+	return m
+}
+
+func fillRect(m *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(m, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func save(name string, m image.Image) {
+	f, err := os.Create(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, m); err != nil {
+		log.Fatal(err)
+	}
+}
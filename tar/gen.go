@@ -0,0 +1,118 @@
+//go:build ignore
+
+// 画出只有输了才会看到的4种格子的模板，在仓库根目录跑 go run tar/gen.go
+// 手上没有输了的截图，颜色是照着没开的格子、旗子和开了的格子挑的，
+// 要保证和别的模板在同一种底色里主色离得够远，截到真的图以后换掉就行
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+)
+
+const w, h = 53, 52
+
+var (
+	unknown = color.RGBA{238, 240, 252, 255} // 和 tar-4 一样
+	opened  = color.RGBA{83, 97, 146, 255}   // 和 tar0 一样
+	blue    = color.RGBA{40, 90, 210, 255}
+	black   = color.RGBA{30, 30, 35, 255}
+	red     = color.RGBA{160, 20, 30, 255}
+	boom    = color.RGBA{230, 40, 40, 255}
+)
+
+// question 5*7 的问号
+var question = []string{
+	".###.",
+	"#...#",
+	"....#",
+	"...#.",
+	"..#..",
+	".....",
+	"..#..",
+}
+
+func main() {
+	for name, m := range map[string]*image.RGBA{
+		"tar/tar-question.png": drawQuestion(),
+		"tar/tar-wrong.png":    drawWrong(),
+		"tar/tar-mine.png":     drawMine(unknown, black),
+		"tar/tar-boom.png":     drawMine(opened, boom),
+	} {
+		f, err := os.Create(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := png.Encode(f, m); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+func fill(c color.RGBA) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+// drawQuestion 没开的格子上一个问号，每个点放大成5*5
+func drawQuestion() *image.RGBA {
+	const k = 5
+	m := fill(unknown)
+	left, top := (w-k*len(question[0]))/2, (h-k*len(question))/2
+	for i, row := range question {
+		for j, c := range row {
+			if c != '#' {
+				continue
+			}
+			for y := 0; y < k; y++ {
+				for x := 0; x < k; x++ {
+					m.SetRGBA(left+j*k+x, top+i*k+y, blue)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// drawWrong 没开的格子上一个叉，插错的旗子输了以后这样显示
+func drawWrong() *image.RGBA {
+	m := fill(unknown)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			u, v := float64(x-w/2), float64(y-h/2)
+			if math.Abs(u) > 17 || math.Abs(v) > 17 {
+				continue
+			}
+			if math.Abs(u-v) < 4 || math.Abs(u+v) < 4 {
+				m.SetRGBA(x, y, red)
+			}
+		}
+	}
+	return m
+}
+
+// drawMine 一个圆加上横竖斜4根刺
+func drawMine(bg, c color.RGBA) *image.RGBA {
+	m := fill(bg)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			u, v := float64(x-w/2), float64(y-h/2)
+			r := math.Hypot(u, v)
+			spike := r < 17 && (math.Abs(u) < 1.5 || math.Abs(v) < 1.5 || math.Abs(math.Abs(u)-math.Abs(v)) < 2)
+			if r < 10 || spike {
+				m.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"taptap/img"
+
+	"gocv.io/x/gocv"
+)
+
+// 标好的小图按 <目录>/<标签名>/*.png 放，标签名见 img.Labels
+// img/testdata/corpus 是一份小的回归集，改了识别以后跑一下 taptap eval img/testdata/corpus
+
+// train 用 tar 目录里的模板和标好的小图训练 kNN，模型写到 -model
+func train(dirs []string) {
+	if *modelFile == "" {
+		log.Fatal("train: need -model")
+	}
+	m := img.NewKNN(*knnK)
+	for _, f := range img.TarFiles {
+		src := gocv.IMRead(tarFile(f.Name), gocv.IMReadColor)
+		if src.Empty() {
+			src.Close()
+			log.Fatal("read tar ", f.Name)
+		}
+		label, _ := img.LabelByName(f.Label)
+		m.Add(src, label)
+		src.Close()
+	}
+	for _, dir := range dirs {
+		err := walkCorpus(dir, func(src gocv.Mat, label img.Label) {
+			m.Add(src, label)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := m.Save(*modelFile); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%v samples -> %v\n", len(m.Samples), *modelFile)
+}
+
+// eval 在回归集上跑一遍当前的识别方式，打印混淆矩阵，行是标注，列是识别结果
+func eval(dirs []string) {
+	if len(dirs) == 0 {
+		log.Fatal("eval: need corpus dir")
+	}
+	empty, dic := getTar()
+	empty.Close()
+	cls := getClassifier(dic)
+
+	matrix := make(map[string]map[string]int)
+	total, right := 0, 0
+	for _, dir := range dirs {
+		err := walkCorpus(dir, func(src gocv.Mat, label img.Label) {
+			got := img.LabelByState(cls.Classify(src))
+			if matrix[label.Name] == nil {
+				matrix[label.Name] = make(map[string]int)
+			}
+			matrix[label.Name][got.Name]++
+			total++
+			if got.Name == label.Name {
+				right++
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Print("   ")
+	for _, l := range img.Labels {
+		fmt.Printf("%5c", l.State.Byte())
+	}
+	fmt.Println()
+	for _, row := range img.Labels {
+		fmt.Printf("%c: ", row.State.Byte())
+		for _, col := range img.Labels {
+			fmt.Printf("%5d", matrix[row.Name][col.Name])
+		}
+		fmt.Println()
+	}
+	if total > 0 {
+		fmt.Printf("%v/%v %.2f%%\n", right, total, float64(right)*100/float64(total))
+	}
+}
+
+// walkCorpus 读取一个目录下所有标好的小图
+func walkCorpus(dir string, fn func(src gocv.Mat, label img.Label)) error {
+	subs, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if !sub.IsDir() {
+			continue
+		}
+		label, err := img.LabelByName(sub.Name())
+		if err != nil {
+			return fmt.Errorf("%v: %w", dir, err)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, sub.Name(), "*.png"))
+		for _, name := range files {
+			src := gocv.IMRead(name, gocv.IMReadColor)
+			if src.Empty() {
+				src.Close()
+				return fmt.Errorf("read %v", name)
+			}
+			fn(src, label)
+			src.Close()
+		}
+	}
+	return nil
+}
+
+// getClassifier 给了 -model 就用训练出来的模型，否则用模板匹配
+func getClassifier(dic img.TargetList) img.Classifier {
+	if *modelFile == "" {
+		return dic
+	}
+	m, err := img.LoadKNN(*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return m
+}